@@ -0,0 +1,273 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"log"
+)
+
+// aliasReferences lists the rules and aliases using the alias name. It reads
+// from the client rather than the cache, as it guards deletes.
+func aliasReferences(client *api.Client, name string) ([]string, error) {
+	var references []string
+
+	rules, err := client.ListFirewallRules()
+	if err != nil {
+		return nil, err
+	}
+	for _, rule := range rules {
+		if endpointUsesAlias(rule.Source, name) || endpointUsesAlias(rule.Destination, name) {
+			references = append(references, fmt.Sprintf("firewall rule %s (%s)", rule.Tracker, rule.Description))
+		}
+	}
+
+	forwards, err := client.ListNatPortForwards()
+	if err != nil {
+		return nil, err
+	}
+	for id, nat := range forwards {
+		if endpointUsesAlias(nat.Source, name) || endpointUsesAlias(nat.Destination, name) ||
+			nat.Target == name || nat.LocalPort == name {
+			references = append(references, fmt.Sprintf("NAT port forward %d (%s)", id, natDescription(nat.Description)))
+		}
+	}
+
+	mappings, err := client.ListNatOutboundMappings()
+	if err != nil {
+		return nil, err
+	}
+	for id, mapping := range mappings {
+		if endpointUsesAlias(mapping.Source, name) || endpointUsesAlias(mapping.Destination, name) ||
+			mapping.SourcePort == name || mapping.DestinationPort == name || mapping.Target == name {
+			references = append(references, fmt.Sprintf("outbound NAT mapping %d (%s)", id, natDescription(mapping.Description)))
+		}
+	}
+
+	oneToOne, err := client.ListNatOneToOneMappings()
+	if err != nil {
+		return nil, err
+	}
+	for id, mapping := range oneToOne {
+		if endpointUsesAlias(mapping.Source, name) || endpointUsesAlias(mapping.Destination, name) {
+			references = append(references, fmt.Sprintf("1:1 NAT mapping %d (%s)", id, natDescription(mapping.Description)))
+		}
+	}
+
+	aliases, err := client.ListAliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		if alias.Name == name {
+			continue
+		}
+		for _, entry := range aliasEntries(aliasValueSource(alias), alias.Details) {
+			if entry["value"] == name {
+				references = append(references, fmt.Sprintf("alias %s", alias.Name))
+				break
+			}
+		}
+	}
+
+	return references, nil
+}
+
+func endpointUsesAlias(endpoint *api.NatSourceOrDestination, name string) bool {
+	if endpoint == nil {
+		return false
+	}
+	return endpoint.Address == name || endpoint.Network == name || endpoint.Port == name
+}
+
+// aliasReferenceSections hold the rules that may use an alias by name. Their
+// locks are taken after the one of sectionAliases.
+var aliasReferenceSections = []string{sectionFilter, sectionNat, sectionNatOutbound, sectionNatOneToOne}
+
+// renameAliasReferences points the rules and aliases using the alias from at
+// to instead. The caller holds the write locks of sectionAliases and of
+// aliasReferenceSections. Calling it again the other way round undoes it.
+func renameAliasReferences(pconf *providerConfiguration, from string, to string) error {
+	renames := []func(*providerConfiguration, string, string) error{
+		renameAliasInAliases,
+		renameAliasInFirewallRules,
+		renameAliasInNatPortForwards,
+		renameAliasInNatOutboundMappings,
+		renameAliasInNatOneToOneMappings,
+	}
+	for _, rename := range renames {
+		if err := rename(pconf, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameAliasInAliases(pconf *providerConfiguration, from string, to string) error {
+	client := pconf.Client
+
+	aliases, err := client.ListAliases()
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliases {
+		if alias.Name == from || alias.Name == to {
+			continue
+		}
+
+		entries := aliasEntries(aliasValueSource(alias), alias.Details)
+		found := false
+		for _, entry := range entries {
+			if entry["value"] == from {
+				entry["value"] = to
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		request := aliasRequestFromAlias(alias)
+		setAliasEntries(request, entries)
+		request["id"] = alias.Name
+
+		log.Printf("[INFO] renaming alias %s to %s in alias %s", from, to, alias.Name)
+		err = client.UpdateAlias(request)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameAliasInFirewallRules(pconf *providerConfiguration, from string, to string) error {
+	client := pconf.Client
+
+	rules, err := client.ListFirewallRules()
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if !endpointUsesAlias(rule.Source, from) && !endpointUsesAlias(rule.Destination, from) {
+			continue
+		}
+
+		renamed := *rule
+		renamed.Source = renameEndpointAlias(rule.Source, from, to)
+		renamed.Destination = renameEndpointAlias(rule.Destination, from, to)
+		request := firewallRuleRequestFromRule(&renamed)
+		request["apply"] = pconf.applyNow()
+
+		log.Printf("[INFO] renaming alias %s to %s in firewall rule %s", from, to, rule.Tracker)
+		err = client.UpdateFirewallRule(request)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameAliasInNatPortForwards(pconf *providerConfiguration, from string, to string) error {
+	forwards, err := pconf.Client.ListNatPortForwards()
+	if err != nil {
+		return err
+	}
+	for id, nat := range forwards {
+		if !endpointUsesAlias(nat.Source, from) && !endpointUsesAlias(nat.Destination, from) &&
+			nat.Target != from && nat.LocalPort != from {
+			continue
+		}
+
+		renamed := *nat
+		renamed.Source = renameEndpointAlias(nat.Source, from, to)
+		renamed.Destination = renameEndpointAlias(nat.Destination, from, to)
+		renamed.Target = renameAlias(nat.Target, from, to)
+		renamed.LocalPort = renameAlias(nat.LocalPort, from, to)
+
+		log.Printf("[INFO] renaming alias %s to %s in NAT port forward %d", from, to, id)
+		err = updateNatPortForward(pconf, id, nat, natPortForwardRequestFromRule(&renamed))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameAliasInNatOutboundMappings(pconf *providerConfiguration, from string, to string) error {
+	client := pconf.Client
+
+	mappings, err := client.ListNatOutboundMappings()
+	if err != nil {
+		return err
+	}
+	for id, mapping := range mappings {
+		if !endpointUsesAlias(mapping.Source, from) && !endpointUsesAlias(mapping.Destination, from) &&
+			mapping.SourcePort != from && mapping.DestinationPort != from && mapping.Target != from {
+			continue
+		}
+
+		renamed := *mapping
+		renamed.Source = renameEndpointAlias(mapping.Source, from, to)
+		renamed.Destination = renameEndpointAlias(mapping.Destination, from, to)
+		renamed.SourcePort = renameAlias(mapping.SourcePort, from, to)
+		renamed.DestinationPort = renameAlias(mapping.DestinationPort, from, to)
+		renamed.Target = renameAlias(mapping.Target, from, to)
+		request := natOutboundMappingRequestFromMapping(&renamed)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		log.Printf("[INFO] renaming alias %s to %s in outbound NAT mapping %d", from, to, id)
+		err = client.UpdateNatOutboundMapping(request)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameAliasInNatOneToOneMappings(pconf *providerConfiguration, from string, to string) error {
+	client := pconf.Client
+
+	mappings, err := client.ListNatOneToOneMappings()
+	if err != nil {
+		return err
+	}
+	for id, mapping := range mappings {
+		if !endpointUsesAlias(mapping.Source, from) && !endpointUsesAlias(mapping.Destination, from) {
+			continue
+		}
+
+		renamed := *mapping
+		renamed.Source = renameEndpointAlias(mapping.Source, from, to)
+		renamed.Destination = renameEndpointAlias(mapping.Destination, from, to)
+		request := natOneToOneRequestFromMapping(&renamed)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		log.Printf("[INFO] renaming alias %s to %s in 1:1 NAT mapping %d", from, to, id)
+		err = client.UpdateNatOneToOneMapping(request)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameEndpointAlias returns a copy of endpoint using to wherever it used
+// from, endpoint itself when it does not use from.
+func renameEndpointAlias(endpoint *api.NatSourceOrDestination, from string, to string) *api.NatSourceOrDestination {
+	if !endpointUsesAlias(endpoint, from) {
+		return endpoint
+	}
+	renamed := *endpoint
+	renamed.Address = renameAlias(endpoint.Address, from, to)
+	renamed.Network = renameAlias(endpoint.Network, from, to)
+	renamed.Port = renameAlias(endpoint.Port, from, to)
+	return &renamed
+}
+
+func renameAlias(value string, from string, to string) string {
+	if value == from {
+		return to
+	}
+	return value
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type Alias struct {
+	Id          int         `json:"id"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Description string      `json:"descr"`
+	Values      interface{} `json:"address"`
+	Details     interface{} `json:"detail"`
+	Urls        interface{} `json:"aliasurl"`
+	Url         string      `json:"url"`
+	UpdateFreq  FlexString  `json:"updatefreq"`
+}
+
+func (c *Client) ListAliases() ([]*Alias, error) {
+	resp, err := c.do(resty.MethodGet, Uri.Alias, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Alias
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+// GetAlias returns nil when there is no alias with this name.
+func (c *Client) GetAlias(name string) (*Alias, error) {
+	resp, err := c.do(resty.MethodGet, Uri.Alias, map[string]string{"name": name}, nil)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Alias
+	if err := resp.decodeList(&result); err != nil {
+		return nil, err
+	}
+
+	for _, alias := range result {
+		if alias.Name == name {
+			return alias, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Client) CreateAlias(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.Alias, nil, request)
+	return err
+}
+
+func (c *Client) UpdateAlias(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.Alias, nil, request)
+	return err
+}
+
+func (c *Client) DeleteAlias(name string) error {
+	_, err := c.do(resty.MethodDelete, Uri.Alias, nil, Request{"id": name})
+	return err
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+// ApplyStatus tells which changes pfSense keeps pending until the next
+// reload. Releases without the field leave Applied nil.
+type ApplyStatus struct {
+	Applied           *bool      `json:"applied"`
+	PendingSubsystems StringList `json:"pending_subsystems"`
+}
+
+func (s *ApplyStatus) Pending() bool {
+	return (s.Applied != nil && !*s.Applied) || len(s.PendingSubsystems) > 0
+}
+
+func (c *Client) GetApplyStatus() (*ApplyStatus, error) {
+	resp, err := c.do(resty.MethodGet, Uri.FilterApply, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result ApplyStatus
+	err = resp.decode(&result)
+	return &result, err
+}
+
+// ApplyFilter reloads the filter, which also applies pending NAT changes.
+func (c *Client) ApplyFilter() error {
+	_, err := c.do(resty.MethodPost, Uri.FilterApply, nil, Request{})
+	return err
+}
+
+func (c *Client) RestartDHCP() error {
+	_, err := c.do(resty.MethodPost, Uri.DHCPRestart, nil, Request{})
+	return err
+}
+
+// RestartDHCPv6 restarts the DHCPv6 server and the router advertisement
+// daemon, which share their settings.
+func (c *Client) RestartDHCPv6() error {
+	_, err := c.do(resty.MethodPost, Uri.DHCPv6Restart, nil, Request{})
+	return err
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	AuthModeLocal = "local"
+	AuthModeJWT   = "jwt"
+	AuthModeToken = "token"
+)
+
+type Config struct {
+	URL          string
+	AuthMode     string
+	Username     string
+	Password     string
+	ClientID     string
+	ClientToken  string
+	TLS          TLSConfig
+	Timeout      time.Duration
+	MaxRetries   int
+	RetryMaxWait time.Duration
+	Parallelism  int
+}
+
+type Client struct {
+	rest        *resty.Client
+	authMode    string
+	username    string
+	password    string
+	clientId    string
+	clientToken string
+
+	// timeout bounds a whole call, retries included.
+	timeout time.Duration
+
+	// slots bounds the number of requests in flight at the same time.
+	slots chan struct{}
+
+	// token is the JWT used on every request. generation is bumped on each
+	// refresh so callers that saw an expired token can tell whether another
+	// caller already replaced it.
+	tokenMutex sync.Mutex
+	token      string
+	generation int
+}
+
+// Request is the body of a create, update or delete call. Field names are
+// the ones expected by the pfSense API.
+type Request map[string]interface{}
+
+type BaseResponse struct {
+	Status  string `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Return  int    `json:"return"`
+}
+
+type Response struct {
+	BaseResponse
+	Data json.RawMessage `json:"data"`
+}
+
+type AuthToken struct {
+	Token string `json:"token"`
+}
+
+func NewClient(config Config) (*Client, error) {
+	tlsconf, err := config.TLS.build(config.URL)
+	if err != nil {
+		return nil, err
+	}
+	rest := resty.New()
+	rest.SetTLSClientConfig(tlsconf)
+	rest.SetTimeout(config.Timeout)
+	rest.SetHostURL(config.URL)
+	rest.SetRetryCount(config.MaxRetries)
+	rest.SetRetryWaitTime(500 * time.Millisecond)
+	rest.SetRetryMaxWaitTime(config.RetryMaxWait)
+	rest.AddRetryCondition(isTransient)
+
+	client := &Client{
+		rest:        rest,
+		authMode:    config.AuthMode,
+		username:    config.Username,
+		password:    config.Password,
+		clientId:    config.ClientID,
+		clientToken: config.ClientToken,
+		timeout:     config.Timeout,
+	}
+
+	if config.Parallelism > 0 {
+		client.slots = make(chan struct{}, config.Parallelism)
+	}
+
+	if client.authMode != AuthModeJWT {
+		return client, nil
+	}
+
+	token, err := client.authenticate()
+	if err != nil {
+		return nil, err
+	}
+
+	client.token = token
+
+	return client, nil
+}
+
+func (c *Client) authenticate() (string, error) {
+	resp, err := c.execute(resty.MethodPost, Uri.Auth, nil, Request{
+		"client-id":    c.clientId,
+		"client-token": c.clientToken,
+	}, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to authenticate! %s", err)
+	}
+
+	var result AuthToken
+	if err := resp.decode(&result); err != nil {
+		return "", err
+	}
+
+	if len(result.Token) <= 0 {
+		return "", fmt.Errorf("failed to get token! message: %s", resp.Message)
+	}
+
+	return result.Token, nil
+}
+
+// isTransient reports failures worth another attempt. Any request is retried
+// when it never reached pfSense, or when pfSense refused it without acting on
+// it: service unavailable, or its config locked by another writer. Other
+// failures are only retried for reads, as a write may have been applied
+// before its response got lost.
+func isTransient(resp *resty.Response, err error) bool {
+	if err != nil {
+		return isDialError(err) || isRead(resp)
+	}
+	if resp.StatusCode() == 200 {
+		return false
+	}
+	if resp.StatusCode() == 503 {
+		return true
+	}
+	body := strings.ToLower(string(resp.Body()))
+	if strings.Contains(body, "config is locked") || strings.Contains(body, "could not obtain lock") {
+		return true
+	}
+	return resp.StatusCode() >= 500 && isRead(resp)
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func isRead(resp *resty.Response) bool {
+	return resp != nil && resp.Request != nil && resp.Request.Method == resty.MethodGet
+}
+
+func (c *Client) currentToken() (string, int) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+	return c.token, c.generation
+}
+
+// refreshToken requests a new token unless the one of generation was already
+// replaced. Concurrent callers wait on the mutex and reuse the first refresh.
+func (c *Client) refreshToken(generation int) error {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	if c.generation != generation {
+		return nil
+	}
+
+	token, err := c.authenticate()
+	if err != nil {
+		return err
+	}
+
+	c.token = token
+	c.generation++
+	return nil
+}
+
+// do runs a request with the current token and replays it once with a fresh
+// token when pfSense rejects the old one as expired.
+func (c *Client) do(method string, uri string, query map[string]string, body interface{}) (*Response, error) {
+	token, generation := c.currentToken()
+	resp, err := c.execute(method, uri, query, body, token)
+
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.StatusCode != 401 || c.authMode != AuthModeJWT {
+		return resp, err
+	}
+
+	if err := c.refreshToken(generation); err != nil {
+		return nil, err
+	}
+
+	token, _ = c.currentToken()
+	return c.execute(method, uri, query, body, token)
+}
+
+func (c *Client) execute(method string, uri string, query map[string]string, body interface{}, token string) (*Response, error) {
+	req := c.rest.R().ForceContentType("application/json")
+	switch c.authMode {
+	case AuthModeLocal:
+		req.SetBasicAuth(c.username, c.password)
+	case AuthModeToken:
+		req.SetHeader("Authorization", c.clientId+" "+c.clientToken)
+	default:
+		if len(token) > 0 {
+			req.SetAuthToken(token)
+		}
+	}
+	if query != nil {
+		req.SetQueryParams(query)
+	}
+	if body != nil {
+		req.SetBody(body)
+	}
+
+	if c.slots != nil {
+		c.slots <- struct{}{}
+		defer func() { <-c.slots }()
+	}
+
+	if c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		req.SetContext(ctx)
+	}
+
+	resp, err := req.Execute(method, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Response{}
+	decodeErr := json.Unmarshal(resp.Body(), result)
+
+	if resp.StatusCode() != 200 {
+		apiErr := &Error{
+			Method:     method,
+			Endpoint:   uri,
+			StatusCode: resp.StatusCode(),
+			Code:       result.Code,
+			Return:     result.Return,
+			Message:    result.Message,
+		}
+		if decodeErr != nil || len(apiErr.Message) <= 0 {
+			apiErr.Message = string(resp.Body())
+		}
+		return nil, apiErr
+	}
+
+	if decodeErr != nil {
+		return nil, fmt.Errorf("invalid response on %s %s: %s, body: %s", method, uri, decodeErr, resp.Body())
+	}
+
+	return result, nil
+}
+
+func (r *Response) decode(out interface{}) error {
+	if len(r.Data) <= 0 || bytes.Equal(r.Data, []byte("null")) {
+		return nil
+	}
+	return json.Unmarshal(r.Data, out)
+}
+
+// decodeList fills out, a pointer to a slice, from the data of a response.
+// The API returns lists either as a JSON array or as an object keyed by the
+// position of each entry, both shapes are accepted.
+func (r *Response) decodeList(out interface{}) error {
+	data := bytes.TrimSpace(r.Data)
+	if len(data) <= 0 || bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	if data[0] != '{' {
+		return json.Unmarshal(data, out)
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		left, errLeft := strconv.Atoi(keys[i])
+		right, errRight := strconv.Atoi(keys[j])
+		if errLeft != nil || errRight != nil {
+			return keys[i] < keys[j]
+		}
+		return left < right
+	})
+
+	list := make([]json.RawMessage, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, entries[key])
+	}
+
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,203 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestDecodeList(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"null", "null", nil},
+		{"array", `["a", "b"]`, []string{"a", "b"}},
+		{"empty array", `[]`, []string{}},
+		{"object by position", `{"1": "b", "0": "a"}`, []string{"a", "b"}},
+		{"numeric order", `{"10": "c", "2": "b", "0": "a"}`, []string{"a", "b", "c"}},
+		{"non numeric keys", `{"wan": "b", "lan": "a"}`, []string{"a", "b"}},
+		{"empty object", `{}`, []string{}},
+		{"surrounding space", " \n[\"a\"]\n", []string{"a"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			r := &Response{Data: []byte(c.data)}
+			if err := r.decodeList(&got); err != nil {
+				t.Fatalf("decodeList(%q) failed: %v", c.data, err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("decodeList(%q) = %#v, want %#v", c.data, got, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeListInvalid(t *testing.T) {
+	for _, data := range []string{`{"0": 1`, `"a"`, `{"0": 1}`} {
+		var got []string
+		r := &Response{Data: []byte(data)}
+		if err := r.decodeList(&got); err == nil {
+			t.Errorf("decodeList(%q) = %#v, want an error", data, got)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(200)
+		case "/unavailable":
+			w.WriteHeader(503)
+		case "/locked":
+			w.WriteHeader(500)
+			w.Write([]byte(`{"message": "Config is locked by another process"}`))
+		case "/failed":
+			w.WriteHeader(500)
+		default:
+			w.WriteHeader(400)
+		}
+	}))
+	defer server.Close()
+
+	rest := resty.New().SetHostURL(server.URL)
+
+	cases := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{resty.MethodGet, "/ok", false},
+		{resty.MethodPost, "/unavailable", true},
+		{resty.MethodPost, "/locked", true},
+		{resty.MethodGet, "/failed", true},
+		{resty.MethodPost, "/failed", false},
+		{resty.MethodGet, "/invalid", false},
+	}
+
+	for _, c := range cases {
+		resp, err := rest.R().Execute(c.method, c.path)
+		if got := isTransient(resp, err); got != c.want {
+			t.Errorf("isTransient(%s %s) = %v, want %v", c.method, c.path, got, c.want)
+		}
+	}
+}
+
+func TestIsTransientDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	rest := resty.New().SetHostURL("http://" + addr)
+	for _, method := range []string{resty.MethodGet, resty.MethodPost} {
+		resp, err := rest.R().Execute(method, "/")
+		if err == nil {
+			t.Fatalf("%s on a closed port succeeded", method)
+		}
+		if !isTransient(resp, err) {
+			t.Errorf("isTransient(%s) = false for %v, want true", method, err)
+		}
+	}
+}
+
+// tokenServer issues a new token on every authentication and accepts only the
+// latest one, none once revoked.
+type tokenServer struct {
+	mutex   sync.Mutex
+	issued  int
+	calls   int
+	revoked bool
+}
+
+func (s *tokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.URL.Path == Uri.Auth {
+		s.issued++
+		w.Write([]byte(`{"code": 200, "data": {"token": "token` + strconv.Itoa(s.issued) + `"}}`))
+		return
+	}
+
+	s.calls++
+	if s.revoked || r.Header.Get("Authorization") != "Bearer token"+strconv.Itoa(s.issued) {
+		w.WriteHeader(401)
+		w.Write([]byte(`{"code": 401, "message": "Authentication failed"}`))
+		return
+	}
+	w.Write([]byte(`{"code": 200, "data": []}`))
+}
+
+func (s *tokenServer) expire() {
+	s.mutex.Lock()
+	s.issued++
+	s.mutex.Unlock()
+}
+
+func TestTokenRefresh(t *testing.T) {
+	server := &tokenServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client, err := NewClient(Config{URL: httpServer.URL, AuthMode: AuthModeJWT})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.ListAliases(); err != nil {
+		t.Fatalf("ListAliases() with a valid token failed: %v", err)
+	}
+
+	server.expire()
+	if _, err := client.ListAliases(); err != nil {
+		t.Fatalf("ListAliases() with an expired token failed: %v", err)
+	}
+	if server.issued != 3 || server.calls != 3 {
+		t.Errorf("expired token: %d tokens issued and %d calls, want 3 and 3", server.issued, server.calls)
+	}
+
+	// A caller that saw the token before the refresh must not replace it
+	// again.
+	if err := client.refreshToken(0); err != nil {
+		t.Fatal(err)
+	}
+	if server.issued != 3 {
+		t.Errorf("stale refresh issued a new token, %d tokens issued", server.issued)
+	}
+}
+
+func TestTokenRefreshOnce(t *testing.T) {
+	server := &tokenServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client, err := NewClient(Config{URL: httpServer.URL, AuthMode: AuthModeJWT})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A token rejected again right after the refresh fails the call
+	// instead of looping.
+	server.revoked = true
+	_, err = client.ListAliases()
+	if !IsAuth(err) {
+		t.Fatalf("ListAliases() error = %v, want an auth error", err)
+	}
+	if server.issued != 2 || server.calls != 2 {
+		t.Errorf("revoked token: %d tokens issued and %d calls, want 2 and 2", server.issued, server.calls)
+	}
+}
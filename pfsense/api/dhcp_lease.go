@@ -0,0 +1,30 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type DHCPLease struct {
+	Ip        string `json:"ip"`
+	Type      string `json:"type"`
+	Mac       string `json:"mac"`
+	Interface string `json:"if"`
+	Starts    string `json:"starts"`
+	Ends      string `json:"ends"`
+	Hostname  string `json:"hostname"`
+	Online    string `json:"online"`
+	State     string `json:"act"`
+}
+
+// ListDHCPLeases returns the active and expired leases of every interface,
+// static mappings included.
+func (c *Client) ListDHCPLeases() ([]*DHCPLease, error) {
+	resp, err := c.do(resty.MethodGet, Uri.DHCPLease, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DHCPLease
+	err = resp.decodeList(&result)
+	return result, err
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type DHCPServer struct {
+	Interface        string             `json:"interface"`
+	Enable           Flag               `json:"enable"`
+	Range            *DHCPRange         `json:"range"`
+	Pools            []*DHCPPool        `json:"pool"`
+	DnsServers       StringList         `json:"dnsserver"`
+	Gateway          string             `json:"gateway"`
+	Domain           string             `json:"domain"`
+	DomainSearchList string             `json:"domainsearchlist"`
+	DefaultLeaseTime FlexString         `json:"defaultleasetime"`
+	MaxLeaseTime     FlexString         `json:"maxleasetime"`
+	DenyUnknown      *FlexString        `json:"denyunknown"`
+	StaticArp        Flag               `json:"staticarp"`
+	FailoverPeerIp   string             `json:"failover_peerip"`
+	NumberOptions    *DHCPNumberOptions `json:"numberoptions"`
+}
+
+type DHCPRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type DHCPPool struct {
+	Range *DHCPRange `json:"range"`
+}
+
+type DHCPNumberOption struct {
+	Number FlexString `json:"number"`
+	Type   string     `json:"type"`
+	Value  string     `json:"value"`
+}
+
+// DHCPNumberOptions holds the custom options of a DHCP server, which pfSense
+// returns as an empty string when there are none.
+type DHCPNumberOptions struct {
+	Items []*DHCPNumberOption `json:"item"`
+}
+
+func (o *DHCPNumberOptions) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	o.Items = nil
+	if len(data) <= 0 || data[0] != '{' {
+		return nil
+	}
+	var value struct {
+		Items []*DHCPNumberOption `json:"item"`
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	o.Items = value.Items
+	return nil
+}
+
+// ListDHCPServers returns the DHCP server settings of every interface. Like
+// the config, the API may key them by interface rather than list them.
+func (c *Client) ListDHCPServers() ([]*DHCPServer, error) {
+	resp, err := c.do(resty.MethodGet, Uri.DHCPServer, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DHCPServer
+	err = resp.decodeServers(&result)
+	return result, err
+}
+
+// decodeServers fills out, a pointer to a slice of DHCP or DHCPv6 servers.
+// Besides a list, the API returns the servers as an object keyed by the
+// interface, which then fills in the interface of entries that lack it.
+func (r *Response) decodeServers(out interface{}) error {
+	data := bytes.TrimSpace(r.Data)
+	if len(data) <= 0 || data[0] != '{' {
+		return r.decodeList(out)
+	}
+
+	var servers map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return err
+	}
+	ifaces := make([]string, 0, len(servers))
+	for iface := range servers {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	list := make([]map[string]json.RawMessage, 0, len(servers))
+	for _, iface := range ifaces {
+		server := servers[iface]
+		if server == nil {
+			continue
+		}
+		if name, ok := server["interface"]; !ok || len(name) <= 0 || string(name) == `""` || string(name) == "null" {
+			server["interface"], _ = json.Marshal(iface)
+		}
+		list = append(list, server)
+	}
+
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (c *Client) UpdateDHCPServer(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.DHCPServer, nil, request)
+	return err
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDHCPNumberOptions(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"none", `{"numberoptions": ""}`, nil},
+		{"null", `{"numberoptions": null}`, nil},
+		{"missing", `{}`, nil},
+		{"items", `{"numberoptions": {"item": [{"number": 66, "type": "text", "value": "dGZ0cA=="}]}}`, []string{"66 text dGZ0cA=="}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var server DHCPServer
+			if err := json.Unmarshal([]byte(c.data), &server); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			if server.NumberOptions != nil {
+				for _, option := range server.NumberOptions.Items {
+					got = append(got, string(option.Number)+" "+option.Type+" "+option.Value)
+				}
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("number options = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeServers(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"empty", "", nil},
+		{"list", `[{"interface": "lan"}, {"interface": "opt1"}]`, []string{"lan", "opt1"}},
+		{"keyed", `{"opt1": {"enable": ""}, "lan": {"enable": ""}}`, []string{"lan", "opt1"}},
+		{"keyed with interface", `{"lan": {"interface": "lan"}}`, []string{"lan"}},
+		{"keyed with empty interface", `{"lan": {"interface": ""}, "opt1": {"interface": null}}`, []string{"lan", "opt1"}},
+		{"keyed skips null", `{"lan": {}, "opt1": null}`, []string{"lan"}},
+		{"empty object", `{}`, []string{}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var servers []*DHCPServer
+			if err := (&Response{Data: []byte(c.data)}).decodeServers(&servers); err != nil {
+				t.Fatal(err)
+			}
+			var v6servers []*DHCPv6Server
+			if err := (&Response{Data: []byte(c.data)}).decodeServers(&v6servers); err != nil {
+				t.Fatal(err)
+			}
+
+			var got, got6 []string
+			if servers != nil {
+				got = []string{}
+			}
+			for _, server := range servers {
+				got = append(got, server.Interface)
+			}
+			if v6servers != nil {
+				got6 = []string{}
+			}
+			for _, server := range v6servers {
+				got6 = append(got6, server.Interface)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("DHCP servers on %v, want %v", got, c.want)
+			}
+			if !reflect.DeepEqual(got6, c.want) {
+				t.Errorf("DHCPv6 servers on %v, want %v", got6, c.want)
+			}
+		})
+	}
+}
+
+func TestDecodeServersInvalid(t *testing.T) {
+	for _, data := range []string{`{"lan": "enabled"}`, `{"lan": {}`, `"lan"`} {
+		var servers []*DHCPServer
+		if err := (&Response{Data: []byte(data)}).decodeServers(&servers); err == nil {
+			t.Errorf("decodeServers(%q) = %v, want an error", data, servers)
+		}
+	}
+}
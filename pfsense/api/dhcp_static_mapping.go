@@ -0,0 +1,72 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type DHCPStaticMapping struct {
+	Id                  int        `json:"id"`
+	Mac                 string     `json:"mac"`
+	Cid                 string     `json:"cid"`
+	Ipaddr              string     `json:"ipaddr"`
+	Hostname            string     `json:"hostname"`
+	Description         string     `json:"descr"`
+	Gateway             string     `json:"gateway"`
+	DnsServers          StringList `json:"dnsserver"`
+	Domain              string     `json:"domain"`
+	DomainSearchList    string     `json:"domainsearchlist"`
+	NtpServers          StringList `json:"ntpserver"`
+	WinsServers         StringList `json:"winsserver"`
+	Filename            string     `json:"filename"`
+	RootPath            string     `json:"rootpath"`
+	ArpTableStaticEntry Flag       `json:"arp_table_static_entry"`
+}
+
+// ListDHCPStaticMappings returns the mappings matching query, which must at
+// least contain the interface.
+func (c *Client) ListDHCPStaticMappings(query map[string]string) ([]*DHCPStaticMapping, error) {
+	resp, err := c.do(resty.MethodGet, Uri.DHCPStaticMapping, query, nil)
+	if isNoStaticMappings(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DHCPStaticMapping
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+func (c *Client) CreateDHCPStaticMapping(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.DHCPStaticMapping, nil, request)
+	return err
+}
+
+func (c *Client) UpdateDHCPStaticMapping(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.DHCPStaticMapping, nil, request)
+	return err
+}
+
+func (c *Client) DeleteDHCPStaticMapping(iface string, id int, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.DHCPStaticMapping, nil, Request{
+		"id":        id,
+		"interface": iface,
+		"apply":     apply,
+	})
+	return err
+}
+
+// isNoStaticMappings reports the API answer for an interface without static
+// mappings. Any other not found, such as an unknown interface or endpoint,
+// stays an error so mappings are not taken as gone.
+func isNoStaticMappings(err error) bool {
+	apiErr, ok := err.(*Error)
+	if !ok || apiErr.Kind() != ErrorNotFound {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "static mapping")
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+// DHCPv6Server holds the DHCPv6 settings of an interface, which pfSense also
+// uses for the router advertisements sent on it.
+type DHCPv6Server struct {
+	Interface        string           `json:"interface"`
+	Enable           Flag             `json:"enable"`
+	Range            *DHCPRange       `json:"range"`
+	PrefixRange      *DHCPPrefixRange `json:"prefixrange"`
+	DnsServers       StringList       `json:"dnsserver"`
+	Domain           string           `json:"domain"`
+	DomainSearchList string           `json:"domainsearchlist"`
+	DefaultLeaseTime FlexString       `json:"defaultleasetime"`
+	MaxLeaseTime     FlexString       `json:"maxleasetime"`
+
+	RaMode             string     `json:"ramode"`
+	RaPriority         string     `json:"rapriority"`
+	RaDnsServers       StringList `json:"radnsserver"`
+	RaSameDnsAsDhcp    Flag       `json:"rasamednsasdhcp6"`
+	RaDomainSearchList string     `json:"radomainsearchlist"`
+}
+
+type DHCPPrefixRange struct {
+	From         string     `json:"from"`
+	To           string     `json:"to"`
+	PrefixLength FlexString `json:"prefixlength"`
+}
+
+type DHCPv6StaticMapping struct {
+	Id          int    `json:"id"`
+	Duid        string `json:"duid"`
+	Ipaddrv6    string `json:"ipaddrv6"`
+	Hostname    string `json:"hostname"`
+	Description string `json:"descr"`
+}
+
+// ListDHCPv6Servers returns the DHCPv6 settings of every interface, keyed by
+// interface or listed like ListDHCPServers.
+func (c *Client) ListDHCPv6Servers() ([]*DHCPv6Server, error) {
+	resp, err := c.do(resty.MethodGet, Uri.DHCPv6Server, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DHCPv6Server
+	err = resp.decodeServers(&result)
+	return result, err
+}
+
+func (c *Client) UpdateDHCPv6Server(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.DHCPv6Server, nil, request)
+	return err
+}
+
+func (c *Client) UpdateRouterAdvertisement(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.RouterAdvertisement, nil, request)
+	return err
+}
+
+// ListDHCPv6StaticMappings is the DHCPv6 counterpart of ListDHCPStaticMappings,
+// query needs the interface as well.
+func (c *Client) ListDHCPv6StaticMappings(query map[string]string) ([]*DHCPv6StaticMapping, error) {
+	resp, err := c.do(resty.MethodGet, Uri.DHCPv6StaticMapping, query, nil)
+	if isNoStaticMappings(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*DHCPv6StaticMapping
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+func (c *Client) CreateDHCPv6StaticMapping(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.DHCPv6StaticMapping, nil, request)
+	return err
+}
+
+func (c *Client) UpdateDHCPv6StaticMapping(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.DHCPv6StaticMapping, nil, request)
+	return err
+}
+
+func (c *Client) DeleteDHCPv6StaticMapping(iface string, id int, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.DHCPv6StaticMapping, nil, Request{
+		"id":        id,
+		"interface": iface,
+		"apply":     apply,
+	})
+	return err
+}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ErrorKind string
+
+const (
+	ErrorUnknown    ErrorKind = "unknown"
+	ErrorNotFound   ErrorKind = "not found"
+	ErrorValidation ErrorKind = "validation"
+	ErrorAuth       ErrorKind = "auth"
+	ErrorConflict   ErrorKind = "conflict"
+	ErrorServer     ErrorKind = "server"
+)
+
+// Error is returned for every API call that did not end with HTTP 200. It
+// carries what pfSense reported back so the caller can surface the reason.
+type Error struct {
+	Method     string
+	Endpoint   string
+	StatusCode int
+	Code       int
+	Return     int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s failed with %s error! code: %d, return: %d, message: %s", e.Method, e.Endpoint, e.Kind(), e.StatusCode, e.Return, e.Message)
+}
+
+// Kind classifies the failure from the HTTP status, falling back on the
+// message for the cases where pfSense answers every rejection with 400.
+func (e *Error) Kind() ErrorKind {
+	message := strings.ToLower(e.Message)
+	switch {
+	case e.StatusCode == 401 || e.StatusCode == 403:
+		return ErrorAuth
+	case e.StatusCode == 404:
+		return ErrorNotFound
+	case e.StatusCode == 409:
+		return ErrorConflict
+	case e.StatusCode >= 500:
+		return ErrorServer
+	case e.StatusCode == 400 || e.StatusCode == 422:
+		if strings.Contains(message, "already exists") || strings.Contains(message, "already in use") || strings.Contains(message, "in use by") {
+			return ErrorConflict
+		}
+		if strings.Contains(message, "does not exist") || strings.Contains(message, "not found") {
+			return ErrorNotFound
+		}
+		return ErrorValidation
+	}
+	return ErrorUnknown
+}
+
+func errorKind(err error) ErrorKind {
+	apiErr, ok := err.(*Error)
+	if !ok {
+		return ErrorUnknown
+	}
+	return apiErr.Kind()
+}
+
+func IsNotFound(err error) bool {
+	return errorKind(err) == ErrorNotFound
+}
+
+func IsValidation(err error) bool {
+	return errorKind(err) == ErrorValidation
+}
+
+func IsAuth(err error) bool {
+	return errorKind(err) == ErrorAuth
+}
+
+func IsConflict(err error) bool {
+	return errorKind(err) == ErrorConflict
+}
+
+func IsServer(err error) bool {
+	return errorKind(err) == ErrorServer
+}
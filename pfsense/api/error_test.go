@@ -0,0 +1,52 @@
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorKind(t *testing.T) {
+	cases := []struct {
+		status  int
+		message string
+		want    ErrorKind
+	}{
+		{401, "", ErrorAuth},
+		{403, "Authentication failed", ErrorAuth},
+		{404, "", ErrorNotFound},
+		{409, "", ErrorConflict},
+		{500, "", ErrorServer},
+		{503, "config is locked", ErrorServer},
+		{400, "Alias name already exists", ErrorConflict},
+		{400, "Alias is already in use", ErrorConflict},
+		{422, "Alias in use by rule", ErrorConflict},
+		{400, "Alias does not exist", ErrorNotFound},
+		{400, "Rule ID NOT FOUND", ErrorNotFound},
+		{400, "Invalid port", ErrorValidation},
+		{422, "", ErrorValidation},
+		{302, "", ErrorUnknown},
+		{0, "not found", ErrorUnknown},
+	}
+
+	for _, c := range cases {
+		err := &Error{StatusCode: c.status, Message: c.message}
+		if got := err.Kind(); got != c.want {
+			t.Errorf("Kind() of %d %q = %q, want %q", c.status, c.message, got, c.want)
+		}
+	}
+}
+
+func TestErrorKindHelpers(t *testing.T) {
+	if !IsNotFound(&Error{StatusCode: 404}) {
+		t.Error("IsNotFound() = false for a 404")
+	}
+	if IsNotFound(errors.New("not found")) {
+		t.Error("IsNotFound() = true for an error not returned by the API")
+	}
+	if IsNotFound(nil) {
+		t.Error("IsNotFound() = true for nil")
+	}
+	if !IsConflict(&Error{StatusCode: 400, Message: "already exists"}) {
+		t.Error("IsConflict() = false for an already exists message")
+	}
+}
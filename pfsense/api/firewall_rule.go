@@ -0,0 +1,60 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type FirewallRule struct {
+	Tracker     FlexString              `json:"tracker"`
+	Type        string                  `json:"type"`
+	Interface   string                  `json:"interface"`
+	IpProtocol  string                  `json:"ipprotocol"`
+	Protocol    string                  `json:"protocol"`
+	Source      *NatSourceOrDestination `json:"source"`
+	Destination *NatSourceOrDestination `json:"destination"`
+	Gateway     string                  `json:"gateway"`
+	Schedule    string                  `json:"sched"`
+	StateType   string                  `json:"statetype"`
+	Direction   string                  `json:"direction"`
+	Floating    Flag                    `json:"floating"`
+	Log         Flag                    `json:"log"`
+	Disabled    Flag                    `json:"disabled"`
+	Description string                  `json:"descr"`
+}
+
+func (c *Client) ListFirewallRules() ([]*FirewallRule, error) {
+	resp, err := c.do(resty.MethodGet, Uri.FirewallRule, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*FirewallRule
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+// CreateFirewallRule returns the created rule, which carries the tracker
+// pfSense assigned to it.
+func (c *Client) CreateFirewallRule(request Request) (*FirewallRule, error) {
+	resp, err := c.do(resty.MethodPost, Uri.FirewallRule, nil, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var result FirewallRule
+	err = resp.decode(&result)
+	return &result, err
+}
+
+func (c *Client) UpdateFirewallRule(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.FirewallRule, nil, request)
+	return err
+}
+
+func (c *Client) DeleteFirewallRule(tracker string, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.FirewallRule, nil, Request{
+		"tracker": tracker,
+		"apply":   apply,
+	})
+	return err
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/go-resty/resty/v2"
+)
+
+type Interface struct {
+	Id          string `json:"id"`
+	Device      string `json:"if"`
+	Description string `json:"descr"`
+	Ipaddr      string `json:"ipaddr"`
+	Subnet      string `json:"subnet"`
+	Ipaddrv6    string `json:"ipaddrv6"`
+	Subnetv6    string `json:"subnetv6"`
+}
+
+// ListInterfaces returns the assigned interfaces keyed by their pfSense name
+// (wan, lan, opt1...). The API returns them as an object keyed by that name,
+// or as an array where each entry carries it as id.
+func (c *Client) ListInterfaces() (map[string]*Interface, error) {
+	resp, err := c.do(resty.MethodGet, Uri.Interface, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]*Interface{}
+	data := bytes.TrimSpace(resp.Data)
+	if len(data) > 0 && data[0] == '{' {
+		err = json.Unmarshal(data, &result)
+		for id, iface := range result {
+			iface.Id = id
+		}
+		return result, err
+	}
+
+	var list []*Interface
+	err = resp.decodeList(&list)
+	for _, iface := range list {
+		result[iface.Id] = iface
+	}
+	return result, err
+}
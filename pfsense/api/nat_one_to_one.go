@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type NatOneToOne struct {
+	Interface     string                  `json:"interface"`
+	IpProtocol    string                  `json:"ipprotocol"`
+	External      string                  `json:"external"`
+	Source        *NatSourceOrDestination `json:"source"`
+	Destination   *NatSourceOrDestination `json:"destination"`
+	Description   string                  `json:"descr"`
+	Disabled      Flag                    `json:"disabled"`
+	NatReflection string                  `json:"natreflection"`
+	NoBinat       Flag                    `json:"nobinat"`
+}
+
+// ListNatOneToOneMappings returns all 1:1 mappings. The position of a
+// mapping in the list is the id expected by update and delete.
+func (c *Client) ListNatOneToOneMappings() ([]*NatOneToOne, error) {
+	resp, err := c.do(resty.MethodGet, Uri.NATOneToOne, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*NatOneToOne
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+func (c *Client) CreateNatOneToOneMapping(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.NATOneToOne, nil, request)
+	return err
+}
+
+func (c *Client) UpdateNatOneToOneMapping(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.NATOneToOne, nil, request)
+	return err
+}
+
+func (c *Client) DeleteNatOneToOneMapping(id int, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.NATOneToOne, nil, Request{
+		"id":    id,
+		"apply": apply,
+	})
+	return err
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type NatOutboundMode struct {
+	Mode string `json:"mode"`
+}
+
+type NatOutboundMapping struct {
+	Interface       string                  `json:"interface"`
+	Protocol        string                  `json:"protocol"`
+	Source          *NatSourceOrDestination `json:"source"`
+	SourcePort      string                  `json:"sourceport"`
+	Destination     *NatSourceOrDestination `json:"destination"`
+	DestinationPort string                  `json:"dstport"`
+	Target          string                  `json:"target"`
+	TargetIp        string                  `json:"targetip"`
+	TargetSubnet    string                  `json:"targetip_subnet"`
+	StaticNatPort   Flag                    `json:"staticnatport"`
+	PoolOptions     string                  `json:"poolopts"`
+	Description     string                  `json:"descr"`
+	Disabled        Flag                    `json:"disabled"`
+	NoSync          Flag                    `json:"nosync"`
+}
+
+func (c *Client) GetNatOutboundMode() (*NatOutboundMode, error) {
+	resp, err := c.do(resty.MethodGet, Uri.NATOutbound, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result NatOutboundMode
+	err = resp.decode(&result)
+	return &result, err
+}
+
+func (c *Client) UpdateNatOutboundMode(mode string, apply bool) error {
+	_, err := c.do(resty.MethodPut, Uri.NATOutbound, nil, Request{
+		"mode":  mode,
+		"apply": apply,
+	})
+	return err
+}
+
+// ListNatOutboundMappings returns all outbound mappings. The position of a
+// mapping in the list is the id expected by update and delete.
+func (c *Client) ListNatOutboundMappings() ([]*NatOutboundMapping, error) {
+	resp, err := c.do(resty.MethodGet, Uri.NATOutboundMapping, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*NatOutboundMapping
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+func (c *Client) CreateNatOutboundMapping(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.NATOutboundMapping, nil, request)
+	return err
+}
+
+func (c *Client) UpdateNatOutboundMapping(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.NATOutboundMapping, nil, request)
+	return err
+}
+
+func (c *Client) DeleteNatOutboundMapping(id int, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.NATOutboundMapping, nil, Request{
+		"id":    id,
+		"apply": apply,
+	})
+	return err
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"github.com/go-resty/resty/v2"
+)
+
+type NatPortForward struct {
+	Interface        string                  `json:"interface"`
+	IpProtocol       string                  `json:"ipprotocol"`
+	Protocol         string                  `json:"protocol"`
+	Source           *NatSourceOrDestination `json:"source"`
+	Destination      *NatSourceOrDestination `json:"destination"`
+	Target           string                  `json:"target"`
+	LocalPort        string                  `json:"local-port"`
+	Description      string                  `json:"descr"`
+	Disabled         Flag                    `json:"disabled"`
+	NatReflection    string                  `json:"natreflection"`
+	AssociatedRuleId string                  `json:"associated-rule-id"`
+	NoRdr            Flag                    `json:"nordr"`
+	NoSync           Flag                    `json:"nosync"`
+}
+
+type NatSourceOrDestination struct {
+	Address string `json:"address"`
+	Any     string `json:"any"`
+	Network string `json:"network"`
+	Not     Flag   `json:"not"`
+	Port    string `json:"port"`
+}
+
+// AddressString returns the address, network or interface macro of d, or
+// "any" when it matches everything.
+func (d *NatSourceOrDestination) AddressString() string {
+	if d == nil {
+		return "any"
+	}
+	var result = ""
+	if len(d.Address) > 0 {
+		result += d.Address
+	} else if len(d.Network) > 0 {
+		result += d.Network
+	} else {
+		result += "any"
+	}
+	return result
+}
+
+func (d *NatSourceOrDestination) PortString() string {
+	var result = "any"
+	if d != nil && len(d.Port) > 0 {
+		result = d.Port
+	}
+	return result
+}
+
+// PortValue returns the port as stored, empty when no port is set.
+func (d *NatSourceOrDestination) PortValue() string {
+	if d == nil {
+		return ""
+	}
+	return d.Port
+}
+
+func (d *NatSourceOrDestination) Inverted() bool {
+	return d != nil && bool(d.Not)
+}
+
+// ListNatPortForwards returns all port forwards. The position of a rule in
+// the list is the id expected by update and delete.
+func (c *Client) ListNatPortForwards() ([]*NatPortForward, error) {
+	resp, err := c.do(resty.MethodGet, Uri.NATPortForward, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*NatPortForward
+	err = resp.decodeList(&result)
+	return result, err
+}
+
+func (c *Client) CreateNatPortForward(request Request) error {
+	_, err := c.do(resty.MethodPost, Uri.NATPortForward, nil, request)
+	return err
+}
+
+func (c *Client) UpdateNatPortForward(request Request) error {
+	_, err := c.do(resty.MethodPut, Uri.NATPortForward, nil, request)
+	return err
+}
+
+func (c *Client) DeleteNatPortForward(id int, apply bool) error {
+	_, err := c.do(resty.MethodDelete, Uri.NATPortForward, nil, Request{
+		"id":    id,
+		"apply": apply,
+	})
+	return err
+}
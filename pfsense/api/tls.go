@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+type TLSConfig struct {
+	Insecure    bool
+	CACert      string
+	CAFile      string
+	Fingerprint string
+	ServerName  string
+	ClientCert  string
+	ClientKey   string
+}
+
+// NormalizeFingerprint strips separators from a SHA-256 fingerprint so both
+// "AB:CD:..." and "abcd..." forms compare equal.
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.Replace(fingerprint, ":", "", -1)
+	fingerprint = strings.Replace(fingerprint, " ", "", -1)
+	return strings.ToLower(fingerprint)
+}
+
+func (t TLSConfig) build(apiUrl string) (*tls.Config, error) {
+	conf := &tls.Config{
+		InsecureSkipVerify: t.Insecure,
+		ServerName:         t.ServerName,
+	}
+
+	roots, err := t.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	conf.RootCAs = roots
+
+	if len(t.ClientCert) > 0 || len(t.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair([]byte(t.ClientCert), []byte(t.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate! %s", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	// A pinned certificate is usually self-signed, so the standard chain
+	// verification is replaced by the fingerprint check. When a CA is given
+	// as well the chain is still verified against it.
+	if len(t.Fingerprint) > 0 {
+		serverName := t.ServerName
+		if len(serverName) <= 0 {
+			parsed, err := url.Parse(apiUrl)
+			if err != nil {
+				return nil, err
+			}
+			serverName = parsed.Hostname()
+		}
+
+		fingerprint := NormalizeFingerprint(t.Fingerprint)
+		insecure := t.Insecure
+		conf.InsecureSkipVerify = true
+		conf.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPinned(rawCerts, fingerprint, roots, serverName, insecure)
+		}
+	}
+
+	return conf, nil
+}
+
+func (t TLSConfig) rootCAs() (*x509.CertPool, error) {
+	if len(t.CACert) <= 0 && len(t.CAFile) <= 0 {
+		return nil, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if len(t.CACert) > 0 && !pool.AppendCertsFromPEM([]byte(t.CACert)) {
+		return nil, fmt.Errorf("failed to parse CA certificate! no PEM certificates found")
+	}
+
+	if len(t.CAFile) > 0 {
+		content, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file! %s", err)
+		}
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("failed to parse CA file %s! no PEM certificates found", t.CAFile)
+		}
+	}
+
+	return pool, nil
+}
+
+func verifyPinned(rawCerts [][]byte, fingerprint string, roots *x509.CertPool, serverName string, insecure bool) error {
+	if len(rawCerts) <= 0 {
+		return fmt.Errorf("server presented no certificate")
+	}
+
+	sum := sha256.Sum256(rawCerts[0])
+	if hex.EncodeToString(sum[:]) != fingerprint {
+		return fmt.Errorf("server certificate fingerprint %s does not match the pinned %s", hex.EncodeToString(sum[:]), fingerprint)
+	}
+
+	if roots == nil || insecure {
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	return err
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCertificate(t *testing.T, name string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerifyPinned(t *testing.T) {
+	raw := testCertificate(t, "pfsense.local")
+	other := testCertificate(t, "other.local")
+	sum := sha256.Sum256(raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	otherCert, err := x509.ParseCertificate(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(otherCert)
+
+	cases := []struct {
+		name        string
+		certs       [][]byte
+		fingerprint string
+		roots       *x509.CertPool
+		serverName  string
+		insecure    bool
+		ok          bool
+	}{
+		{"no certificate", nil, fingerprint, nil, "pfsense.local", false, false},
+		{"fingerprint mismatch", [][]byte{other}, fingerprint, nil, "pfsense.local", false, false},
+		{"fingerprint only", [][]byte{raw}, fingerprint, nil, "pfsense.local", false, true},
+		{"trusted root", [][]byte{raw}, fingerprint, roots, "pfsense.local", false, true},
+		{"wrong server name", [][]byte{raw}, fingerprint, roots, "firewall.local", false, false},
+		{"untrusted root", [][]byte{raw}, fingerprint, otherRoots, "pfsense.local", false, false},
+		{"insecure skips the chain", [][]byte{raw}, fingerprint, otherRoots, "firewall.local", true, true},
+		{"insecure keeps the pin", [][]byte{other}, fingerprint, nil, "pfsense.local", true, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := verifyPinned(c.certs, c.fingerprint, c.roots, c.serverName, c.insecure)
+			if c.ok && err != nil {
+				t.Errorf("verifyPinned() failed: %v", err)
+			}
+			if !c.ok && err == nil {
+				t.Error("verifyPinned() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Flag is a pfSense option stored as an empty element, which the API returns
+// as "" when it is set and omits otherwise.
+type Flag bool
+
+func (f *Flag) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	*f = Flag(!bytes.Equal(data, []byte("null")) && !bytes.Equal(data, []byte("false")))
+	return nil
+}
+
+// FlexString accepts both JSON strings and numbers, as the API is not
+// consistent about ids and trackers.
+type FlexString string
+
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*s = ""
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*s = FlexString(value)
+		return nil
+	}
+	*s = FlexString(data)
+	return nil
+}
+
+// StringList accepts a JSON array of strings or a single string, the API
+// returns repeated elements of the config as an array only when there is more
+// than one of them.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*l = nil
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*l = nil
+		if len(value) > 0 {
+			*l = StringList{value}
+		}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
@@ -0,0 +1,41 @@
+package api
+
+// Uri lists every endpoint of the pfSense API used by the provider. New
+// endpoints are added here and wrapped by a typed method on Client.
+var Uri = struct {
+	DHCPStaticMapping   string
+	Auth                string
+	NATPortForward      string
+	Alias               string
+	FilterApply         string
+	DHCPRestart         string
+	FirewallRule        string
+	NATOutbound         string
+	NATOutboundMapping  string
+	NATOneToOne         string
+	Interface           string
+	DHCPServer          string
+	DHCPv6Server        string
+	DHCPv6StaticMapping string
+	RouterAdvertisement string
+	DHCPLease           string
+	DHCPv6Restart       string
+}{
+	"/services/dhcpd/static_mapping",
+	"/access_token",
+	"/firewall/nat/port_forward",
+	"/firewall/alias",
+	"/firewall/apply",
+	"/services/dhcpd/restart",
+	"/firewall/rule",
+	"/firewall/nat/outbound",
+	"/firewall/nat/outbound/mapping",
+	"/firewall/nat/one_to_one",
+	"/interface",
+	"/services/dhcpd",
+	"/services/dhcpdv6",
+	"/services/dhcpdv6/static_mapping",
+	"/services/dhcpdv6/router_advertisement",
+	"/services/dhcpd/lease",
+	"/services/dhcpdv6/restart",
+}
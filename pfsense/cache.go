@@ -0,0 +1,297 @@
+package pfsense
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+)
+
+// readCache keeps the full listing of each config section for the lifetime
+// of the provider process, so a refresh issues one list call per section no
+// matter how many resources or data sources read it. Concurrent readers of a
+// section wait for the same fetch.
+type readCache struct {
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newReadCache() *readCache {
+	return &readCache{
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+func (c *readCache) get(section string, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	entry, ok := c.entries[section]
+	if ok {
+		c.mutex.Unlock()
+		<-entry.done
+		return entry.value, entry.err
+	}
+
+	entry = &cacheEntry{done: make(chan struct{})}
+	c.entries[section] = entry
+	c.mutex.Unlock()
+
+	entry.value, entry.err = fetch()
+	close(entry.done)
+
+	if entry.err != nil {
+		c.Invalidate(section)
+	}
+	return entry.value, entry.err
+}
+
+func (c *readCache) Invalidate(section string) {
+	c.mutex.Lock()
+	delete(c.entries, section)
+	c.mutex.Unlock()
+}
+
+// write runs f with the write lock of section and drops the cached listing
+// of that section, and of the sections it changes as a side effect,
+// afterwards. Lookups inside f must go to the client directly, as the cache
+// would keep returning the state before the write.
+func (p *providerConfiguration) write(section string, f func() error) error {
+	return p.Locks.Write(section, func() error {
+		defer func() {
+			p.Cache.Invalidate(section)
+			for _, dependent := range dependentSections(section) {
+				p.Cache.Invalidate(dependent)
+			}
+		}()
+		return f()
+	})
+}
+
+// writeSections runs f with the write locks of all sections, taken in the
+// order given. Callers taking several locks keep to the order of the section
+// constants to stay clear of deadlocks.
+func (p *providerConfiguration) writeSections(sections []string, f func() error) error {
+	if len(sections) <= 0 {
+		return f()
+	}
+	return p.write(sections[0], func() error {
+		return p.writeSections(sections[1:], f)
+	})
+}
+
+// dependentSections returns the sections pfSense also changes on a write to
+// section: port forwards add and remove their associated filter rules. The
+// DHCP server and static mappings of an interface are written under its
+// section, which is part of the server listing, and the static mappings show
+// up in the lease listing. The same goes for DHCPv6, where the router
+// advertisements are kept with the server.
+func dependentSections(section string) []string {
+	switch {
+	case section == sectionNat:
+		return []string{sectionFilter}
+	case strings.HasPrefix(section, dhcpSection("")):
+		return []string{sectionDhcpServers, sectionDhcpLeases}
+	case strings.HasPrefix(section, dhcpv6Section("")):
+		return []string{sectionDhcpv6Servers}
+	}
+	return nil
+}
+
+// cached returns the listing of section, fetching it on the first call.
+func (p *providerConfiguration) cached(section string, fetch func() (interface{}, error)) (interface{}, error) {
+	var value interface{}
+	err := p.Locks.Read(section, func() error {
+		var err error
+		value, err = p.Cache.get(section, fetch)
+		return err
+	})
+	return value, err
+}
+
+func (p *providerConfiguration) aliases() ([]*api.Alias, error) {
+	value, err := p.cached(sectionAliases, func() (interface{}, error) {
+		return p.Client.ListAliases()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.Alias), nil
+}
+
+func (p *providerConfiguration) alias(name string) (*api.Alias, error) {
+	aliases, err := p.aliases()
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		if alias.Name == name {
+			return alias, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *providerConfiguration) natPortForwards() ([]*api.NatPortForward, error) {
+	value, err := p.cached(sectionNat, func() (interface{}, error) {
+		return p.Client.ListNatPortForwards()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.NatPortForward), nil
+}
+
+func (p *providerConfiguration) natOutboundMappings() ([]*api.NatOutboundMapping, error) {
+	value, err := p.cached(sectionNatOutbound, func() (interface{}, error) {
+		return p.Client.ListNatOutboundMappings()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.NatOutboundMapping), nil
+}
+
+func (p *providerConfiguration) natOneToOneMappings() ([]*api.NatOneToOne, error) {
+	value, err := p.cached(sectionNatOneToOne, func() (interface{}, error) {
+		return p.Client.ListNatOneToOneMappings()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.NatOneToOne), nil
+}
+
+func (p *providerConfiguration) firewallRules() ([]*api.FirewallRule, error) {
+	value, err := p.cached(sectionFilter, func() (interface{}, error) {
+		return p.Client.ListFirewallRules()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.FirewallRule), nil
+}
+
+func (p *providerConfiguration) firewallRule(tracker string) (*api.FirewallRule, error) {
+	rules, err := p.firewallRules()
+	if err != nil {
+		return nil, err
+	}
+	return findFirewallRule(rules, tracker), nil
+}
+
+func (p *providerConfiguration) dhcpStaticMappings(iface string) ([]*api.DHCPStaticMapping, error) {
+	value, err := p.cached(dhcpSection(iface), func() (interface{}, error) {
+		return p.Client.ListDHCPStaticMappings(map[string]string{"interface": iface})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.DHCPStaticMapping), nil
+}
+
+func (p *providerConfiguration) dhcpStaticMapping(iface string, key dhcpMappingKey) (*api.DHCPStaticMapping, error) {
+	mappings, err := p.dhcpStaticMappings(iface)
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range mappings {
+		if key.matches(mapping) {
+			return mapping, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *providerConfiguration) interfaces() (map[string]*api.Interface, error) {
+	value, err := p.cached(sectionInterfaces, func() (interface{}, error) {
+		return p.Client.ListInterfaces()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(map[string]*api.Interface), nil
+}
+
+func (p *providerConfiguration) dhcpServers() ([]*api.DHCPServer, error) {
+	value, err := p.cached(sectionDhcpServers, func() (interface{}, error) {
+		return p.Client.ListDHCPServers()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.DHCPServer), nil
+}
+
+func (p *providerConfiguration) dhcpServer(iface string) (*api.DHCPServer, error) {
+	servers, err := p.dhcpServers()
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		if server.Interface == iface {
+			return server, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *providerConfiguration) dhcpv6Servers() ([]*api.DHCPv6Server, error) {
+	value, err := p.cached(sectionDhcpv6Servers, func() (interface{}, error) {
+		return p.Client.ListDHCPv6Servers()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.DHCPv6Server), nil
+}
+
+func (p *providerConfiguration) dhcpv6Server(iface string) (*api.DHCPv6Server, error) {
+	servers, err := p.dhcpv6Servers()
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		if server.Interface == iface {
+			return server, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *providerConfiguration) dhcpv6StaticMappings(iface string) ([]*api.DHCPv6StaticMapping, error) {
+	value, err := p.cached(dhcpv6Section(iface), func() (interface{}, error) {
+		return p.Client.ListDHCPv6StaticMappings(map[string]string{"interface": iface})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.DHCPv6StaticMapping), nil
+}
+
+func (p *providerConfiguration) dhcpv6StaticMapping(iface string, duid string) (*api.DHCPv6StaticMapping, error) {
+	mappings, err := p.dhcpv6StaticMappings(iface)
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range mappings {
+		if strings.EqualFold(mapping.Duid, duid) {
+			return mapping, nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *providerConfiguration) dhcpLeases() ([]*api.DHCPLease, error) {
+	value, err := p.cached(sectionDhcpLeases, func() (interface{}, error) {
+		return p.Client.ListDHCPLeases()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*api.DHCPLease), nil
+}
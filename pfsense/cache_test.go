@@ -0,0 +1,135 @@
+package pfsense
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestReadCache(t *testing.T) {
+	cache := newReadCache()
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := cache.get(sectionAliases, fetch)
+		if err != nil || value != 1 {
+			t.Fatalf("get() = %v, %v, want the first fetch", value, err)
+		}
+	}
+
+	cache.Invalidate(sectionAliases)
+	if value, _ := cache.get(sectionAliases, fetch); value != 2 {
+		t.Errorf("get() after Invalidate = %v, want a new fetch", value)
+	}
+
+	if value, _ := cache.get(sectionFilter, fetch); value != 3 {
+		t.Errorf("get() of another section = %v, want its own fetch", value)
+	}
+}
+
+func TestReadCacheError(t *testing.T) {
+	cache := newReadCache()
+	failed := errors.New("failed")
+
+	_, err := cache.get(sectionNat, func() (interface{}, error) { return nil, failed })
+	if err != failed {
+		t.Fatalf("get() error = %v, want %v", err, failed)
+	}
+
+	// A failed fetch is not kept, the next reader tries again.
+	value, err := cache.get(sectionNat, func() (interface{}, error) { return "rules", nil })
+	if err != nil || value != "rules" {
+		t.Errorf("get() after a failure = %v, %v, want a new fetch", value, err)
+	}
+}
+
+func TestReadCacheConcurrent(t *testing.T) {
+	cache := newReadCache()
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		mutex.Lock()
+		fetches++
+		mutex.Unlock()
+		<-release
+		return "rules", nil
+	}
+
+	var wg sync.WaitGroup
+	values := make([]interface{}, 5)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = cache.get(sectionFilter, fetch)
+		}(i)
+	}
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("%d fetches for concurrent readers, want 1", fetches)
+	}
+	for i, value := range values {
+		if value != "rules" {
+			t.Errorf("reader %d got %v", i, value)
+		}
+	}
+}
+
+func TestWriteInvalidatesDependentSections(t *testing.T) {
+	pconf := &providerConfiguration{
+		Locks: newLockManager(),
+		Cache: newReadCache(),
+	}
+	sections := []string{
+		sectionAliases, sectionNat, sectionFilter,
+		sectionDhcpServers, sectionDhcpLeases, sectionDhcpv6Servers,
+		dhcpSection("lan"), dhcpSection("opt1"), dhcpv6Section("lan"),
+	}
+	fill := func() {
+		for _, section := range sections {
+			pconf.Cache.get(section, func() (interface{}, error) { return section, nil })
+		}
+	}
+	dropped := func() []string {
+		var result []string
+		for _, section := range sections {
+			pconf.Cache.mutex.Lock()
+			_, ok := pconf.Cache.entries[section]
+			pconf.Cache.mutex.Unlock()
+			if !ok {
+				result = append(result, section)
+			}
+		}
+		return result
+	}
+
+	cases := []struct {
+		section string
+		want    []string
+	}{
+		{sectionAliases, []string{sectionAliases}},
+		{sectionNat, []string{sectionNat, sectionFilter}},
+		{sectionFilter, []string{sectionFilter}},
+		{dhcpSection("lan"), []string{sectionDhcpServers, sectionDhcpLeases, dhcpSection("lan")}},
+		{dhcpv6Section("lan"), []string{sectionDhcpv6Servers, dhcpv6Section("lan")}},
+	}
+
+	for _, c := range cases {
+		fill()
+		err := pconf.write(c.section, func() error { return errors.New("failed") })
+		if err == nil {
+			t.Fatal("write() dropped the error of f")
+		}
+		if got := dropped(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("write(%s) invalidated %v, want %v", c.section, got, c.want)
+		}
+	}
+}
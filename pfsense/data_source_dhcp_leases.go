@@ -0,0 +1,137 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func dataSourceDhcpLeases() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceDhcpLeasesRead,
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"mac_prefix": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"hostname_regex": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.ValidateRegexp,
+			},
+			"leases": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"ip": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"mac": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"hostname": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"start": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"end": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"state": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"online": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"interface": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"static": {
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func dataSourceDhcpLeasesRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	iface := d.Get("interface").(string)
+	macPrefix := normalizeMac(d.Get("mac_prefix").(string))
+	hostnameRegex, err := regexp.Compile(d.Get("hostname_regex").(string))
+	if err != nil {
+		return fmt.Errorf("invalid hostname_regex: %s", err)
+	}
+
+	data, err := pconf.dhcpLeases()
+	if err != nil {
+		return err
+	}
+
+	leases := make([]interface{}, 0)
+	for _, lease := range data {
+		if !dhcpLeaseMatches(lease, iface, macPrefix, hostnameRegex) {
+			continue
+		}
+		leases = append(leases, map[string]interface{}{
+			"ip":        lease.Ip,
+			"mac":       lease.Mac,
+			"hostname":  lease.Hostname,
+			"start":     lease.Starts,
+			"end":       lease.Ends,
+			"state":     lease.State,
+			"online":    strings.EqualFold(lease.Online, "online"),
+			"interface": lease.Interface,
+			"static":    strings.EqualFold(lease.Type, "static"),
+		})
+	}
+
+	err = d.Set("leases", leases)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(strconv.Itoa(schema.HashString(fmt.Sprintf("%s/%s/%s", iface, macPrefix, hostnameRegex))))
+	return nil
+}
+
+func dhcpLeaseMatches(lease *api.DHCPLease, iface string, macPrefix string, hostnameRegex *regexp.Regexp) bool {
+	if len(iface) > 0 && lease.Interface != iface {
+		return false
+	}
+	if len(macPrefix) > 0 && !strings.HasPrefix(normalizeMac(lease.Mac), macPrefix) {
+		return false
+	}
+	return hostnameRegex.MatchString(lease.Hostname)
+}
+
+// normalizeMac lowercases mac and uses colons as separator, so prefixes
+// match whatever notation they are written in.
+func normalizeMac(mac string) string {
+	return strings.ToLower(strings.Replace(mac, "-", ":", -1))
+}
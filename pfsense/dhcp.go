@@ -0,0 +1,140 @@
+package pfsense
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Helpers shared by the DHCP and DHCPv6 resources.
+
+func stringList(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		result = append(result, value.(string))
+	}
+	return result
+}
+
+// splitDomainSearchList splits the search list as pfSense stores it, domains
+// separated by semicolons.
+func splitDomainSearchList(list string) []string {
+	result := make([]string, 0)
+	for _, domain := range strings.Split(list, ";") {
+		domain = strings.TrimSpace(domain)
+		if len(domain) > 0 {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+func leaseTime(value api.FlexString) int {
+	seconds, err := strconv.Atoi(string(value))
+	if err != nil {
+		return 0
+	}
+	return seconds
+}
+
+// interfaceSubnet returns the IPv4 or IPv6 network of iface, nil when it has
+// no static address of that family, as with dhcp or track6 interfaces.
+func interfaceSubnet(iface *api.Interface, ipv6 bool) *net.IPNet {
+	if iface == nil {
+		return nil
+	}
+	addr, subnet, size := iface.Ipaddr, iface.Subnet, 32
+	if ipv6 {
+		addr, subnet, size = iface.Ipaddrv6, iface.Subnetv6, 128
+	}
+	ip := net.ParseIP(addr)
+	bits, err := strconv.Atoi(subnet)
+	if ip == nil || (ip.To4() == nil) != ipv6 || err != nil || bits < 0 || bits > size {
+		return nil
+	}
+	if !ipv6 {
+		ip = ip.To4()
+	}
+	mask := net.CIDRMask(bits, size)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+}
+
+// ipInRange compares the 16 byte forms, so it works for both families.
+func ipInRange(ip net.IP, from string, to string) bool {
+	start := net.ParseIP(from).To16()
+	end := net.ParseIP(to).To16()
+	if start == nil || end == nil {
+		return false
+	}
+	ip = ip.To16()
+	return bytes.Compare(ip, start) >= 0 && bytes.Compare(ip, end) <= 0
+}
+
+// findStaticMapping returns the index of the one entry out of count that
+// matches, -1 when none does. key and iface only describe the lookup in the
+// error.
+func findStaticMapping(key string, iface string, count int, matches func(i int) bool) (int, error) {
+	found := -1
+	for i := 0; i < count; i++ {
+		if !matches(i) {
+			continue
+		}
+		if found >= 0 {
+			return -1, fmt.Errorf("more then one mapping found for %s on %s! ids: %d, %d", key, iface, found, i)
+		}
+		found = i
+	}
+	return found, nil
+}
+
+// checkStaticAddress rejects an address in field that is outside the subnet
+// of the interface or inside one of the dynamic ranges of its server, which
+// pfSense would only refuse at apply time. Interfaces without a static
+// address of that family, and servers without a range, are not checked.
+//
+// The check runs at plan time against the interface and server settings
+// currently on pfSense, read through the cache. Changes to those in the same
+// plan are not seen: moving a pool out of the way and adding a mapping into
+// the freed addresses has to be applied in two steps.
+func checkStaticAddress(d *schema.ResourceDiff, meta interface{}, field string, ipv6 bool, ranges func(pconf *providerConfiguration, iface string) ([]*api.DHCPRange, error)) error {
+	if meta == nil || !d.NewValueKnown("interface") || !d.NewValueKnown(field) {
+		return nil
+	}
+	if len(d.Id()) > 0 && !d.HasChange("interface") && !d.HasChange(field) {
+		return nil
+	}
+
+	pconf := meta.(*providerConfiguration)
+	iface := d.Get("interface").(string)
+	ip := net.ParseIP(d.Get(field).(string))
+	if ip == nil || (ip.To4() == nil) != ipv6 {
+		return nil
+	}
+
+	interfaces, err := pconf.interfaces()
+	if err != nil {
+		return err
+	}
+	if subnet := interfaceSubnet(interfaces[iface], ipv6); subnet != nil && !subnet.Contains(ip) {
+		return fmt.Errorf("%s %s is outside the subnet %s of interface %s", field, ip, subnet, iface)
+	}
+
+	dynamic, err := ranges(pconf, iface)
+	if err != nil {
+		return err
+	}
+	for _, pool := range dynamic {
+		if pool != nil && ipInRange(ip, pool.From, pool.To) {
+			return fmt.Errorf("%s %s is inside the dynamic range %s-%s of interface %s", field, ip, pool.From, pool.To, iface)
+		}
+	}
+
+	return nil
+}
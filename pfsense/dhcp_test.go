@@ -0,0 +1,169 @@
+package pfsense
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+)
+
+func TestInterfaceSubnet(t *testing.T) {
+	cases := []struct {
+		name  string
+		iface *api.Interface
+		ipv6  bool
+		want  string
+	}{
+		{"none", nil, false, ""},
+		{"ipv4", &api.Interface{Ipaddr: "192.168.1.1", Subnet: "24"}, false, "192.168.1.0/24"},
+		{"ipv4 host bits", &api.Interface{Ipaddr: "10.1.2.3", Subnet: "16"}, false, "10.1.0.0/16"},
+		{"ipv6", &api.Interface{Ipaddrv6: "fd00::1", Subnetv6: "64"}, true, "fd00::/64"},
+		{"dhcp", &api.Interface{Ipaddr: "dhcp", Ipaddrv6: "dhcp6"}, false, ""},
+		{"track6", &api.Interface{Ipaddr: "192.168.1.1", Subnet: "24", Ipaddrv6: "track6"}, true, ""},
+		{"no ipv6", &api.Interface{Ipaddr: "192.168.1.1", Subnet: "24"}, true, ""},
+		{"ipv6 in the ipv4 field", &api.Interface{Ipaddr: "fd00::1", Subnet: "64"}, false, ""},
+		{"mask too large", &api.Interface{Ipaddr: "192.168.1.1", Subnet: "33"}, false, ""},
+		{"no mask", &api.Interface{Ipaddr: "192.168.1.1"}, false, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			subnet := interfaceSubnet(c.iface, c.ipv6)
+			got := ""
+			if subnet != nil {
+				got = subnet.String()
+			}
+			if got != c.want {
+				t.Errorf("interfaceSubnet() = %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestIpInRange(t *testing.T) {
+	cases := []struct {
+		ip   string
+		from string
+		to   string
+		want bool
+	}{
+		{"192.168.1.100", "192.168.1.100", "192.168.1.199", true},
+		{"192.168.1.199", "192.168.1.100", "192.168.1.199", true},
+		{"192.168.1.150", "192.168.1.100", "192.168.1.199", true},
+		{"192.168.1.99", "192.168.1.100", "192.168.1.199", false},
+		{"192.168.1.200", "192.168.1.100", "192.168.1.199", false},
+		{"192.168.2.150", "192.168.1.100", "192.168.1.199", false},
+		{"fd00::150", "fd00::100", "fd00::1ff", true},
+		{"fd00::2", "fd00::100", "fd00::1ff", false},
+		{"192.168.1.150", "", "", false},
+	}
+
+	for _, c := range cases {
+		if got := ipInRange(net.ParseIP(c.ip), c.from, c.to); got != c.want {
+			t.Errorf("ipInRange(%s, %s, %s) = %v, want %v", c.ip, c.from, c.to, got, c.want)
+		}
+	}
+}
+
+// dhcpTestConfiguration returns a provider configuration whose cache already
+// holds the interfaces and DHCP servers, so no call reaches the API.
+func dhcpTestConfiguration(interfaces map[string]*api.Interface, servers []*api.DHCPServer) *providerConfiguration {
+	pconf := &providerConfiguration{
+		Locks: newLockManager(),
+		Cache: newReadCache(),
+	}
+	pconf.Cache.get(sectionInterfaces, func() (interface{}, error) { return interfaces, nil })
+	pconf.Cache.get(sectionDhcpServers, func() (interface{}, error) { return servers, nil })
+	return pconf
+}
+
+func TestCheckStaticAddress(t *testing.T) {
+	pconf := dhcpTestConfiguration(map[string]*api.Interface{
+		"lan":  {Ipaddr: "192.168.1.1", Subnet: "24"},
+		"opt1": {Ipaddr: "dhcp"},
+	}, []*api.DHCPServer{
+		{
+			Interface: "lan",
+			Range:     &api.DHCPRange{From: "192.168.1.100", To: "192.168.1.149"},
+			Pools: []*api.DHCPPool{
+				nil,
+				{Range: &api.DHCPRange{From: "192.168.1.200", To: "192.168.1.219"}},
+			},
+		},
+	})
+
+	cases := []struct {
+		name   string
+		iface  string
+		ipaddr string
+		err    string
+	}{
+		{"inside the subnet", "lan", "192.168.1.50", ""},
+		{"outside the subnet", "lan", "192.168.2.50", "outside the subnet 192.168.1.0/24"},
+		{"inside the range", "lan", "192.168.1.120", "inside the dynamic range 192.168.1.100-192.168.1.149"},
+		{"inside a pool", "lan", "192.168.1.200", "inside the dynamic range 192.168.1.200-192.168.1.219"},
+		{"between range and pool", "lan", "192.168.1.150", ""},
+		{"interface without static address", "opt1", "10.0.0.5", ""},
+		{"unknown interface", "opt2", "10.0.0.5", ""},
+	}
+
+	resource := resourceDhcpStaticMapping()
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			config := terraform.NewResourceConfigRaw(map[string]interface{}{
+				"interface": c.iface,
+				"mac":       "00:11:22:33:44:55",
+				"ipaddr":    c.ipaddr,
+			})
+			_, err := resource.Diff(nil, config, pconf)
+			if len(c.err) <= 0 {
+				if err != nil {
+					t.Errorf("diff failed: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("diff error = %v, want %q", err, c.err)
+			}
+		})
+	}
+}
+
+func TestCheckStaticAddressUnchanged(t *testing.T) {
+	pconf := dhcpTestConfiguration(map[string]*api.Interface{
+		"lan": {Ipaddr: "192.168.1.1", Subnet: "24"},
+	}, nil)
+
+	// A mapping that pfSense already holds is not checked again when
+	// neither its interface nor its address change, the subnet may have
+	// moved since.
+	state := &terraform.InstanceState{
+		ID: "lan/00:11:22:33:44:55",
+		Attributes: map[string]string{
+			"id":        "lan/00:11:22:33:44:55",
+			"interface": "lan",
+			"mac":       "00:11:22:33:44:55",
+			"ipaddr":    "10.0.0.5",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"interface": "lan",
+		"mac":       "00:11:22:33:44:55",
+		"ipaddr":    "10.0.0.5",
+		"hostname":  "host",
+	})
+	if _, err := resourceDhcpStaticMapping().Diff(state, config, pconf); err != nil {
+		t.Errorf("diff of an unchanged address failed: %v", err)
+	}
+
+	config = terraform.NewResourceConfigRaw(map[string]interface{}{
+		"interface": "lan",
+		"mac":       "00:11:22:33:44:55",
+		"ipaddr":    "10.0.0.6",
+	})
+	if _, err := resourceDhcpStaticMapping().Diff(state, config, pconf); err == nil {
+		t.Error("diff of a changed address outside the subnet succeeded")
+	}
+}
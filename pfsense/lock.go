@@ -0,0 +1,70 @@
+package pfsense
+
+import (
+	"sync"
+)
+
+const (
+	sectionAliases       = "aliases"
+	sectionNat           = "nat"
+	sectionNatOutbound   = "nat/outbound"
+	sectionNatOneToOne   = "nat/onetoone"
+	sectionFilter        = "filter"
+	sectionInterfaces    = "interfaces"
+	sectionDhcpServers   = "dhcpd"
+	sectionDhcpv6Servers = "dhcpdv6"
+	sectionDhcpLeases    = "dhcpd/leases"
+)
+
+// dhcpSection covers the DHCP settings of iface, the server as well as its
+// static mappings. sectionDhcpServers only keys the cached server listing.
+func dhcpSection(iface string) string {
+	return "dhcp/" + iface
+}
+
+// dhcpv6Section covers the DHCPv6 server, router advertisements and static
+// mappings of iface.
+func dhcpv6Section(iface string) string {
+	return "dhcpv6/" + iface
+}
+
+// lockManager serialises writes to one pfSense config section while reads of
+// that section, and any access to other sections, run in parallel.
+type lockManager struct {
+	mutex    sync.Mutex
+	sections map[string]*sync.RWMutex
+}
+
+func newLockManager() *lockManager {
+	return &lockManager{
+		sections: map[string]*sync.RWMutex{},
+	}
+}
+
+func (m *lockManager) section(name string) *sync.RWMutex {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lock, ok := m.sections[name]
+	if !ok {
+		lock = &sync.RWMutex{}
+		m.sections[name] = lock
+	}
+	return lock
+}
+
+// Write runs f while holding the write lock of section.
+func (m *lockManager) Write(section string, f func() error) error {
+	lock := m.section(section)
+	lock.Lock()
+	defer lock.Unlock()
+	return f()
+}
+
+// Read runs f while holding a read lock of section.
+func (m *lockManager) Read(section string, f func() error) error {
+	lock := m.section(section)
+	lock.RLock()
+	defer lock.RUnlock()
+	return f()
+}
@@ -1,62 +1,165 @@
 package pfsense
 
 import (
-	"crypto/tls"
 	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
-	"sync"
+	"regexp"
 	"time"
 
-	"github.com/go-resty/resty/v2"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 )
 
 type providerConfiguration struct {
-	Client *resty.Client
-	Mutex  *sync.Mutex
-	Cond   *sync.Cond
+	Client        *api.Client
+	Locks         *lockManager
+	Cache         *readCache
+	Timeout       time.Duration
+	DeferredApply bool
+
+	// natPutUnsupported is set once the API rejected a PUT on port forwards
+	// as not allowed. Only read and written with the NAT write lock held.
+	natPutUnsupported bool
 }
 
 // Provider - Terrafrom properties for proxmox
 func Provider() *schema.Provider {
 	return &schema.Provider{
 		Schema: map[string]*schema.Schema{
+			"pf_auth_mode": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_AUTH_MODE", api.AuthModeJWT),
+				ValidateFunc: validation.StringInSlice([]string{api.AuthModeLocal, api.AuthModeJWT, api.AuthModeToken}, false),
+			},
+			"pf_username": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_USERNAME", nil),
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"pf_password": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_PASSWORD", nil),
+				Sensitive:    true,
+				ValidateFunc: validation.StringIsNotEmpty,
+			},
 			"pf_client_id": {
-				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("PF_CLIENT_ID", nil),
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_CLIENT_ID", nil),
 				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
 			"pf_api_token": {
-				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("PF_API_TOKEN", nil),
-				Sensitive:   true,
-				ValidateFunc:  validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_API_TOKEN", nil),
+				Sensitive:    true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
 			"pf_api_url": {
-				Type:        schema.TypeString,
-				Required:    true,
-				DefaultFunc: schema.EnvDefaultFunc("PF_API_URL", nil),
+				Type:         schema.TypeString,
+				Required:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_API_URL", nil),
 				ValidateFunc: validation.IsURLWithHTTPS,
 			},
 			"pf_tls_insecure": {
-				Type:     schema.TypeBool,
-				Optional: true,
-				Default:  false,
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PF_TLS_INSECURE", false),
+			},
+			"pf_ca_cert": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("PF_CA_CERT", nil),
+				ConflictsWith: []string{"pf_ca_file"},
+				ValidateFunc:  validation.StringIsNotWhiteSpace,
+			},
+			"pf_ca_file": {
+				Type:          schema.TypeString,
+				Optional:      true,
+				DefaultFunc:   schema.EnvDefaultFunc("PF_CA_FILE", nil),
+				ConflictsWith: []string{"pf_ca_cert"},
+				ValidateFunc:  validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"pf_tls_fingerprint": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_TLS_FINGERPRINT", nil),
+				ValidateFunc: validateFingerprint,
+			},
+			"pf_tls_server_name": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_TLS_SERVER_NAME", nil),
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"pf_client_cert": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_CLIENT_CERT", nil),
+				RequiredWith: []string{"pf_client_key"},
+				ValidateFunc: validation.StringIsNotWhiteSpace,
+			},
+			"pf_client_key": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_CLIENT_KEY", nil),
+				Sensitive:    true,
+				RequiredWith: []string{"pf_client_cert"},
+				ValidateFunc: validation.StringIsNotWhiteSpace,
 			},
 			"pf_timeout": {
-				Type:     schema.TypeInt,
-				Optional: true,
-				Default:  300,
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      300,
 				ValidateFunc: validation.IntAtLeast(0),
 			},
+			"pf_max_retries": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_MAX_RETRIES", 5),
+				ValidateFunc: validation.IntAtLeast(0),
+			},
+			"pf_retry_max_wait": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_RETRY_MAX_WAIT", 30),
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"pf_parallelism": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				DefaultFunc:  schema.EnvDefaultFunc("PF_PARALLELISM", 4),
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"pf_deferred_apply": {
+				Type:        schema.TypeBool,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("PF_DEFERRED_APPLY", false),
+			},
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"pfsense_dhcp_static_mapping": resourceDhcpStaticMapping(),
-			"pfsense_nat_port_forward": resourceNatPortForward(),
-			"pfsense_alias": resourceAlias(),
+			"pfsense_dhcp_static_mapping":   resourceDhcpStaticMapping(),
+			"pfsense_nat_port_forward":      resourceNatPortForward(),
+			"pfsense_alias":                 resourceAlias(),
+			"pfsense_apply":                 resourceApply(),
+			"pfsense_firewall_rule":         resourceFirewallRule(),
+			"pfsense_rule_order":            resourceRuleOrder(),
+			"pfsense_nat_outbound_mode":     resourceNatOutboundMode(),
+			"pfsense_nat_outbound_mapping":  resourceNatOutboundMapping(),
+			"pfsense_nat_one_to_one":        resourceNatOneToOne(),
+			"pfsense_dhcp_server":           resourceDhcpServer(),
+			"pfsense_dhcpv6_server":         resourceDhcpv6Server(),
+			"pfsense_dhcpv6_static_mapping": resourceDhcpv6StaticMapping(),
+			"pfsense_router_advertisement":  resourceRouterAdvertisement(),
+		},
+
+		DataSourcesMap: map[string]*schema.Resource{
+			"pfsense_dhcp_leases": dataSourceDhcpLeases(),
 		},
 
 		ConfigureFunc: providerConfigure,
@@ -64,67 +167,107 @@ func Provider() *schema.Provider {
 }
 
 func providerConfigure(d *schema.ResourceData) (interface{}, error) {
-	client, err := getClient(d.Get("pf_api_url").(string), d.Get("pf_client_id").(string), d.Get("pf_api_token").(string), d.Get("pf_tls_insecure").(bool), d.Get("pf_timeout").(int))
+	authMode := d.Get("pf_auth_mode").(string)
+	if err := validateAuthMode(d, authMode); err != nil {
+		return nil, err
+	}
+
+	client, err := api.NewClient(api.Config{
+		URL:         d.Get("pf_api_url").(string),
+		AuthMode:    authMode,
+		Username:    d.Get("pf_username").(string),
+		Password:    d.Get("pf_password").(string),
+		ClientID:    d.Get("pf_client_id").(string),
+		ClientToken: d.Get("pf_api_token").(string),
+		TLS: api.TLSConfig{
+			Insecure:    d.Get("pf_tls_insecure").(bool),
+			CACert:      d.Get("pf_ca_cert").(string),
+			CAFile:      d.Get("pf_ca_file").(string),
+			Fingerprint: d.Get("pf_tls_fingerprint").(string),
+			ServerName:  d.Get("pf_tls_server_name").(string),
+			ClientCert:  d.Get("pf_client_cert").(string),
+			ClientKey:   d.Get("pf_client_key").(string),
+		},
+		Timeout:      time.Duration(d.Get("pf_timeout").(int)) * time.Second,
+		MaxRetries:   d.Get("pf_max_retries").(int),
+		RetryMaxWait: time.Duration(d.Get("pf_retry_max_wait").(int)) * time.Second,
+		Parallelism:  d.Get("pf_parallelism").(int),
+	})
 	if err != nil {
 		return nil, err
 	}
-	var mut sync.Mutex
 	return &providerConfiguration{
-		Client: client,
-		Mutex:  &mut,
-		Cond:   sync.NewCond(&mut),
+		Client:        client,
+		Locks:         newLockManager(),
+		Cache:         newReadCache(),
+		Timeout:       time.Duration(d.Get("pf_timeout").(int)) * time.Second,
+		DeferredApply: d.Get("pf_deferred_apply").(bool),
 	}, nil
 }
 
-func getClient(apiUrl string, clientId string, apiKey string, tlsInsecure bool, timeout int) (*resty.Client, error) {
-	tlsconf := &tls.Config{InsecureSkipVerify: true}
-	if !tlsInsecure {
-		tlsconf = nil
-	}
-	client := resty.New()
-	client.SetTLSClientConfig(tlsconf)
-	client.SetTimeout(time.Duration(timeout) * time.Second)
-	client.SetHostURL(apiUrl)
-
-	authRequest := map[string]interface{}{
-		"client-id":    clientId,
-		"client-token": apiKey,
-	}
-
-	resp, err := client.R().
-		SetBody(authRequest).
-		SetResult(&AuthTokenResponse{}).
-		ForceContentType("application/json").
-		Post(PFSenseApiUri.Auth)
-	if err != nil {
-		return nil, err
-	}
+// applyNow tells whether a write should be applied by pfSense right away.
+// With deferred apply the change stays pending in pfSense until pfsense_apply
+// reloads the filter and restarts the DHCP servers once.
+func (p *providerConfiguration) applyNow() bool {
+	return !p.DeferredApply
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("failed to authenticate! code: %d", resp.StatusCode())
-	}
+// applyDeleteNow is applyNow for deletes, which are always applied right
+// away. pfsense_apply depends on the rules, so Terraform destroys it before
+// them and its reload would never pick the deletes up.
+func (p *providerConfiguration) applyDeleteNow() bool {
+	return true
+}
 
-	var result = resp.Result().(*AuthTokenResponse)
+// validateAuthMode rejects credentials that do not belong to the selected
+// mode, so a half migrated provider block fails at plan time.
+func validateAuthMode(d *schema.ResourceData, authMode string) error {
+	localSet := len(d.Get("pf_username").(string)) > 0 || len(d.Get("pf_password").(string)) > 0
+	clientSet := len(d.Get("pf_client_id").(string)) > 0 || len(d.Get("pf_api_token").(string)) > 0
 
-	if len(result.Data.Token) <= 0 {
-		return nil, fmt.Errorf("failed to get token! data %s, result: %s", resp, result)
+	switch authMode {
+	case api.AuthModeLocal:
+		if len(d.Get("pf_username").(string)) <= 0 || len(d.Get("pf_password").(string)) <= 0 {
+			return fmt.Errorf("pf_auth_mode %q requires pf_username and pf_password", authMode)
+		}
+		if clientSet {
+			return fmt.Errorf("pf_client_id and pf_api_token can not be used with pf_auth_mode %q", authMode)
+		}
+	default:
+		if len(d.Get("pf_client_id").(string)) <= 0 || len(d.Get("pf_api_token").(string)) <= 0 {
+			return fmt.Errorf("pf_auth_mode %q requires pf_client_id and pf_api_token", authMode)
+		}
+		if localSet {
+			return fmt.Errorf("pf_username and pf_password can not be used with pf_auth_mode %q", authMode)
+		}
 	}
-
-	client.SetAuthToken(result.Data.Token)
-
-	return client, nil
+	return nil
 }
 
-type ApiBaseResponse struct {
-	Status  string `json:"status"`
-	Code    int	   `json:"code"`
-	Message string `json:"message"`
-	Return  int	   `json:"return"`
+var fingerprintRegex = regexp.MustCompile("^[0-9a-f]{64}$")
+
+func validateFingerprint(i interface{}, k string) ([]string, []error) {
+	v, ok := i.(string)
+	if !ok {
+		return nil, []error{fmt.Errorf("expected type of %s to be string", k)}
+	}
+	if !fingerprintRegex.MatchString(api.NormalizeFingerprint(v)) {
+		return nil, []error{fmt.Errorf("expected %s to be a SHA-256 fingerprint, got: %s", k, v)}
+	}
+	return nil, nil
 }
 
-type AuthTokenResponse struct {
-	ApiBaseResponse
-	Data 	struct{
-		Token	string `json:"token"`
-	}  `json:"data"`
+// waitForObject polls until a freshly written object shows up in the API, as
+// pfSense may answer a write before the configuration is readable again.
+func waitForObject(timeout time.Duration, exists func() (bool, error)) error {
+	return resource.Retry(timeout, func() *resource.RetryError {
+		found, err := exists()
+		if err != nil {
+			return resource.NonRetryableError(err)
+		}
+		if !found {
+			return resource.RetryableError(fmt.Errorf("object is not visible yet"))
+		}
+		return nil
+	})
 }
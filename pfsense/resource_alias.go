@@ -2,16 +2,21 @@ package pfsense
 
 import (
 	"fmt"
-	"github.com/go-resty/resty/v2"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"net"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
-	"time"
 )
 
 var aliasNameRegex = regexp.MustCompile("^([A-Za-z0-9_]+)$")
 
+var aliasTypes = []string{"host", "network", "port", "url", "url_ports", "urltable", "urltable_ports"}
+
 func resourceAlias() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceAliasCreate,
@@ -21,6 +26,7 @@ func resourceAlias() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceAliasCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"name": {
@@ -34,15 +40,22 @@ func resourceAlias() *schema.Resource {
 			"type": {
 				Type:         schema.TypeString,
 				Required:     true,
-				ValidateFunc: validation.StringInSlice([]string{"host", "network", "port"}, false),
+				ValidateFunc: validation.StringInSlice(aliasTypes, false),
+			},
+			"update_frequency": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Computed:     true,
+				ValidateFunc: validation.IntAtLeast(1),
 			},
 			"desc": {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
 			"value": {
-				Type:     schema.TypeList,
+				Type:     schema.TypeSet,
 				Required: true,
+				Set:      aliasValueHash,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
 						"value": {
@@ -63,153 +76,95 @@ func resourceAlias() *schema.Resource {
 
 func resourceAliasCreate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-
 	client := pconf.Client
-	lock := pconf.Mutex
 
 	name := d.Get("name").(string)
 
-	lock.Lock()
-	data, err := fetchAlias(client, name)
-	if err != nil {
-		lock.Unlock()
-		return err
-	}
-
-	if data != nil {
-		lock.Unlock()
-		return fmt.Errorf("alias for this name already exists! data: %s", data)
-	}
+	err := pconf.write(sectionAliases, func() error {
+		data, err := client.GetAlias(name)
+		if err != nil {
+			return err
+		}
 
-	var request = map[string]interface{}{
-		"name": name,
-		"type": d.Get("type"),
-	}
+		if data != nil {
+			return fmt.Errorf("alias for this name already exists! data: %v", data)
+		}
 
-	var description = d.Get("desc")
-	if description != nil {
-		request["descr"] = description
-	}
+		var request = aliasRequest(d)
+		request["name"] = name
 
-	var values = d.Get("value").([]interface{})
-	valueCount := len(values)
-	if valueCount == 1 {
-		request["address"] = values[0].(map[string]interface{})["value"]
-		request["detail"] = values[0].(map[string]interface{})["details"]
-	} else {
-		request["address"] = make([]interface{}, valueCount)
-		request["detail"] = make([]interface{}, valueCount)
-		for i, value := range values {
-			request["address"].([]interface{})[i] = value.(map[string]interface{})["value"]
-			request["detail"].([]interface{})[i] = value.(map[string]interface{})["details"]
+		err = client.CreateAlias(request)
+		if err != nil {
+			return err
 		}
-	}
 
-	resp, err := client.R().
-		SetBody(request).
-		Post(PFSenseApiUri.Alias)
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			data, err1 := client.GetAlias(name)
+			return data != nil, err1
+		})
+		if err != nil {
+			return fmt.Errorf("allias for this name do not exists! name: %s, error: %s", name, err)
+		}
 
+		d.SetId(aliasResourceId(name))
+		return nil
+	})
 	if err != nil {
-		lock.Unlock()
 		return err
 	}
 
-	if resp.StatusCode() != 200 {
-		lock.Unlock()
-		return fmt.Errorf("invalid response code on create: %d, response: %s", resp.StatusCode(), resp.Body())
-	}
-
-	time.Sleep(100 * time.Millisecond)
-
-	data, err1 := fetchAlias(client, name)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("allias for this name do not exists! data: %s", data)
-	}
-
-	d.SetId(aliasResourceId(name))
-	lock.Unlock()
-
-	err = resourceAliasRead(d, meta)
-	return err
+	return resourceAliasRead(d, meta)
 }
 
 func resourceAliasRead(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-	lock := pconf.Mutex
-	client := pconf.Client
 
-	lock.Lock()
 	name, err := parseAliasResourceId(d.Id())
 	if err != nil {
 		d.SetId("")
-		lock.Unlock()
 		return err
 	}
 
-	data, err1 := fetchAlias(client, name)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
+	data, err := pconf.alias(name)
+	if err != nil {
+		return err
 	}
 	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("alias for this name do not exists! name: %s, data: %s", name, data)
+		log.Printf("[WARN] alias %s not found, removing it from state", name)
+		d.SetId("")
+		return nil
 	}
 
 	d.SetId(aliasResourceId(name))
 
-	err2 := d.Set("name", name)
-	if err2 != nil {
-		return err2
+	err = d.Set("name", name)
+	if err != nil {
+		return err
 	}
-	err2 = d.Set("type", data.Type)
-	if err2 != nil {
-		return err2
+	err = d.Set("type", data.Type)
+	if err != nil {
+		return err
 	}
-	err2 = d.Set("descr", data.Description)
-
-	values := make([]map[string]string, 0)
-	switch typ := data.Values.(type) {
-	case []string:
-		for i, value := range data.Values.([]string) {
-			configValue := map[string]string{
-				"value":   value,
-				"details": data.Details.([]string)[i],
-			}
-			values = append(values, configValue)
-		}
-	case string:
-		valueArray := strings.Split(data.Values.(string), " ")
-		detailsArray := strings.Split(data.Details.(string), "||")
-		for i, value := range valueArray {
-			configValue := map[string]string{
-				"value":   value,
-				"details": detailsArray[i],
-			}
-			values = append(values, configValue)
-		}
-	default:
-		return fmt.Errorf("result from data value list is not from supported type: %s", typ)
+	err = d.Set("desc", data.Description)
+	if err != nil {
+		return err
+	}
+	err = d.Set("update_frequency", aliasUpdateFrequency(data))
+	if err != nil {
+		return err
 	}
 
-	err2 = d.Set("value", values)
-	if err2 != nil {
-		return err2
+	values := make([]interface{}, 0)
+	for _, entry := range aliasEntries(aliasValueSource(data), data.Details) {
+		values = append(values, entry)
 	}
-	lock.Unlock()
 
-	return nil
+	return d.Set("value", values)
 }
 
 func resourceAliasDelete(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
 	client := pconf.Client
-	lock := pconf.Mutex
 
 	name, err := parseAliasResourceId(d.Id())
 	if err != nil {
@@ -217,61 +172,102 @@ func resourceAliasDelete(d *schema.ResourceData, meta interface{}) error {
 		return err
 	}
 
-	lock.Lock()
-	data, err1 := fetchAlias(client, name)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("alias for this id do not exists! name: %s, data: %s", name, data)
-	}
-
-	var request = map[string]interface{}{
-		"id": name,
-	}
+	return pconf.write(sectionAliases, func() error {
+		data, err := client.GetAlias(name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			log.Printf("[WARN] alias %s already deleted", name)
+			return nil
+		}
 
-	resp, err := client.R().
-		SetBody(request).
-		Delete(PFSenseApiUri.Alias)
+		references, err := aliasReferences(client, name)
+		if err != nil {
+			return err
+		}
+		if len(references) > 0 {
+			return fmt.Errorf("alias %s is still in use! references: %s", name, strings.Join(references, ", "))
+		}
 
-	lock.Unlock()
-	if err != nil {
+		err = client.DeleteAlias(name)
+		if api.IsNotFound(err) {
+			return nil
+		}
 		return err
-	}
-
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code on delete: %d, response %s, request: %s", resp.StatusCode(), resp.Body(), request)
-	}
-
-	return nil
+	})
 }
 
 func resourceAliasUpdate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
 	client := pconf.Client
-	lock := pconf.Mutex
+
 	name, err := parseAliasResourceId(d.Id())
 	if err != nil {
 		d.SetId("")
 		return err
 	}
 
-	lock.Lock()
-	data, err1 := fetchAlias(client, name)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("alias for this id do not exists! name: %s, data: %s", name, data)
+	// A rename also rewrites the rules and aliases using the old name, which
+	// needs the locks of their sections as well.
+	newName := d.Get("name").(string)
+	sections := []string{sectionAliases}
+	if newName != name {
+		sections = append(sections, aliasReferenceSections...)
 	}
 
-	var request = map[string]interface{}{
-		"id":   name,
-		"name": d.Get("name"),
+	return pconf.writeSections(sections, func() error {
+		data, err := client.GetAlias(name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return fmt.Errorf("alias for this id do not exists! name: %s, data: %v", name, data)
+		}
+
+		if newName != name {
+			existing, err := client.GetAlias(newName)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return fmt.Errorf("alias for this name already exists! data: %v", existing)
+			}
+		}
+
+		var request = aliasRequest(d)
+		request["id"] = name
+		request["name"] = newName
+
+		err = client.UpdateAlias(request)
+		if err != nil {
+			return err
+		}
+		if newName == name {
+			return nil
+		}
+
+		err = renameAliasReferences(pconf, name, newName)
+		if err != nil {
+			rollback := aliasRequestFromAlias(data)
+			rollback["id"] = newName
+			rollbackErr := client.UpdateAlias(rollback)
+			if rollbackErr == nil {
+				rollbackErr = renameAliasReferences(pconf, newName, name)
+			}
+			if rollbackErr != nil {
+				return fmt.Errorf("%s; rollback of the rename of alias %s failed: %s", err, name, rollbackErr)
+			}
+			return err
+		}
+
+		d.SetId(aliasResourceId(newName))
+		return nil
+	})
+}
+
+func aliasRequest(d *schema.ResourceData) api.Request {
+	var request = api.Request{
 		"type": d.Get("type"),
 	}
 
@@ -280,34 +276,53 @@ func resourceAliasUpdate(d *schema.ResourceData, meta interface{}) error {
 		request["descr"] = description
 	}
 
-	var values = d.Get("value").([]interface{})
-	valueCount := len(values)
-	if valueCount == 1 {
-		request["address"] = values[0].(map[string]interface{})["value"]
-		request["detail"] = values[0].(map[string]interface{})["details"]
-	} else {
-		request["address"] = make([]interface{}, valueCount)
-		request["detail"] = make([]interface{}, valueCount)
-		for i, value := range values {
-			request["address"].([]interface{})[i] = value.(map[string]interface{})["value"]
-			request["detail"].([]interface{})[i] = value.(map[string]interface{})["details"]
+	if isAliasUrlTable(d.Get("type").(string)) {
+		if frequency, ok := d.GetOk("update_frequency"); ok {
+			request["updatefreq"] = frequency
 		}
 	}
 
-	resp, err := client.R().
-		SetBody(request).
-		Put(PFSenseApiUri.Alias)
+	var values = d.Get("value").(*schema.Set).List()
+	entries := make([]map[string]interface{}, len(values))
+	for i, value := range values {
+		entries[i] = value.(map[string]interface{})
+	}
+	setAliasEntries(request, entries)
 
-	lock.Unlock()
-	if err != nil {
-		return err
+	return request
+}
+
+// aliasRequestFromAlias builds the request that writes data back unchanged.
+func aliasRequestFromAlias(data *api.Alias) api.Request {
+	var request = api.Request{
+		"name":  data.Name,
+		"type":  data.Type,
+		"descr": data.Description,
 	}
 
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code: %d, response: %s, request: %s", resp.StatusCode(), resp.Body(), request)
+	if isAliasUrlTable(data.Type) && len(data.UpdateFreq) > 0 {
+		request["updatefreq"] = string(data.UpdateFreq)
 	}
 
-	return nil
+	setAliasEntries(request, aliasEntries(aliasValueSource(data), data.Details))
+	return request
+}
+
+func setAliasEntries(request api.Request, entries []map[string]interface{}) {
+	if len(entries) == 1 {
+		request["address"] = entries[0]["value"]
+		request["detail"] = entries[0]["details"]
+		return
+	}
+
+	addresses := make([]interface{}, len(entries))
+	details := make([]interface{}, len(entries))
+	for i, entry := range entries {
+		addresses[i] = entry["value"]
+		details[i] = entry["details"]
+	}
+	request["address"] = addresses
+	request["detail"] = details
 }
 
 func aliasResourceId(name string) string {
@@ -322,58 +337,196 @@ func parseAliasResourceId(resId string) (name string, err error) {
 	return
 }
 
-func fetchAlias(client *resty.Client, aliasName string) (*ReadAlias, error) {
-	resp, err := client.R().
-		SetQueryParams(map[string]string{
-			"name": aliasName,
-		}).
-		SetResult(&ReadAliasMapResponse{}).
-		ForceContentType("application/json").
-		Get(PFSenseApiUri.Alias)
+// resourceAliasCustomizeDiff checks every value against the type of the alias.
+// Values not known until apply are left to pfSense.
+func resourceAliasCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	aliasType := d.Get("type").(string)
+	if !d.NewValueKnown("type") || !d.NewValueKnown("value") {
+		return nil
+	}
 
-	if err != nil {
-		resp, err = client.R().
-			SetQueryParams(map[string]string{
-				"name": aliasName,
-			}).
-			SetResult(&ReadAliasArrayResponse{}).
-			ForceContentType("application/json").
-			Get(PFSenseApiUri.Alias)
+	// Left unset, update_frequency keeps what pfSense stored, which is then
+	// also what a change away from the urltable types leaves behind.
+	if d.Get("update_frequency").(int) != 0 && !isAliasUrlTable(aliasType) {
+		if d.HasChange("update_frequency") {
+			return fmt.Errorf("update_frequency is only supported by urltable and urltable_ports aliases, not %s", aliasType)
+		}
+		if err := d.SetNew("update_frequency", 0); err != nil {
+			return err
+		}
+	}
 
-		if err != nil {
-			return nil, err
+	values := d.Get("value").(*schema.Set).List()
+	if isAliasUrlTable(aliasType) && len(values) != 1 {
+		return fmt.Errorf("%s alias must have exactly one value, got %d", aliasType, len(values))
+	}
+
+	for _, value := range values {
+		entry, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		address, _ := entry["value"].(string)
+		if len(address) <= 0 {
+			continue
+		}
+		if err := validateAliasValue(aliasType, address); err != nil {
+			return err
 		}
-		return nil, nil
 	}
+	return nil
+}
+
+var aliasFqdnRegex = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)+[A-Za-z]{2,63}\.?$`)
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("invalid response code on fetch: %d", resp.StatusCode())
+var aliasPortRegex = regexp.MustCompile("^([0-9]+)(:([0-9]+))?$")
+
+// validateAliasValue accepts what pfSense accepts for an entry of an alias of
+// aliasType. Any type but the URL ones may also nest another alias by name,
+// whether that alias exists is only known to pfSense at apply time.
+func validateAliasValue(aliasType string, value string) error {
+	switch aliasType {
+	case "url", "url_ports", "urltable", "urltable_ports":
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) <= 0 {
+			return fmt.Errorf("invalid value %q for %s alias: must be a http or https URL", value, aliasType)
+		}
+		return nil
 	}
 
-	var result = resp.Result()
+	if aliasNameRegex.MatchString(value) && !aliasPortRegex.MatchString(value) {
+		return nil
+	}
 
-	for k := range result.(*ReadAliasMapResponse).Data {
-		return result.(*ReadAliasMapResponse).Data[k], nil
+	switch aliasType {
+	case "host":
+		if net.ParseIP(value) != nil || isAliasIpRange(value) || aliasFqdnRegex.MatchString(value) {
+			return nil
+		}
+		return fmt.Errorf("invalid value %q for host alias: must be an IP address, IP range, FQDN or alias name", value)
+	case "network":
+		if _, _, err := net.ParseCIDR(value); err == nil {
+			return nil
+		}
+		if net.ParseIP(value) != nil || isAliasIpRange(value) || aliasFqdnRegex.MatchString(value) {
+			return nil
+		}
+		return fmt.Errorf("invalid value %q for network alias: must be a CIDR, IP address, IP range, FQDN or alias name", value)
+	case "port":
+		if isAliasPort(value) {
+			return nil
+		}
+		return fmt.Errorf("invalid value %q for port alias: must be a port, port range (from:to) or alias name", value)
 	}
+	return nil
+}
 
-	return nil, nil
+func isAliasIpRange(value string) bool {
+	parts := strings.Split(value, "-")
+	return len(parts) == 2 && net.ParseIP(parts[0]) != nil && net.ParseIP(parts[1]) != nil
 }
 
-type ReadAliasArrayResponse struct {
-	ApiBaseResponse
-	Data []*ReadAlias `json:"data"`
+func isAliasPort(value string) bool {
+	match := aliasPortRegex.FindStringSubmatch(value)
+	if match == nil {
+		return false
+	}
+	for _, part := range []string{match[1], match[3]} {
+		if len(part) <= 0 {
+			continue
+		}
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return false
+		}
+	}
+	return true
+}
+
+func isAliasUrlTable(aliasType string) bool {
+	return aliasType == "urltable" || aliasType == "urltable_ports"
 }
 
-type ReadAliasMapResponse struct {
-	ApiBaseResponse
-	Data map[string]*ReadAlias `json:"data"`
+// aliasValueSource returns the entries of an alias. URL aliases keep the
+// configured URLs apart from the addresses pfSense downloaded from them.
+func aliasValueSource(data *api.Alias) interface{} {
+	switch {
+	case (data.Type == "url" || data.Type == "url_ports") && data.Urls != nil:
+		return data.Urls
+	case isAliasUrlTable(data.Type) && len(data.Url) > 0:
+		return data.Url
+	}
+	return data.Values
 }
 
-type ReadAlias struct {
-	Id          int         `json:"id"`
-	Name        string      `json:"name"`
-	Type        string      `json:"type"`
-	Description string      `json:"descr"`
-	Values      interface{} `json:"address"`
-	Details     interface{} `json:"detail"`
+func aliasUpdateFrequency(data *api.Alias) int {
+	if !isAliasUrlTable(data.Type) {
+		return 0
+	}
+	frequency, err := strconv.Atoi(string(data.UpdateFreq))
+	if err != nil {
+		return 0
+	}
+	return frequency
+}
+
+// aliasValueHash keys the entries of an alias by their value, so a change of
+// the details shows as an update of the entry and the order does not matter.
+func aliasValueHash(v interface{}) int {
+	entry, ok := v.(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	value, _ := entry["value"].(string)
+	return schema.HashString(value)
+}
+
+// aliasEntries pairs the values of an alias with their details. The API
+// returns both either as arrays or as strings, values separated by spaces and
+// details by "||". Missing details are left empty and surplus ones dropped.
+func aliasEntries(values interface{}, details interface{}) []map[string]interface{} {
+	valueList := aliasStrings(values, func(s string) []string {
+		return strings.Fields(s)
+	})
+	detailList := aliasStrings(details, func(s string) []string {
+		return strings.Split(s, "||")
+	})
+
+	entries := make([]map[string]interface{}, 0, len(valueList))
+	for i, value := range valueList {
+		detail := ""
+		if i < len(detailList) {
+			detail = detailList[i]
+		}
+		entries = append(entries, map[string]interface{}{
+			"value":   value,
+			"details": detail,
+		})
+	}
+	return entries
+}
+
+func aliasStrings(raw interface{}, split func(string) []string) []string {
+	switch typed := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		if len(typed) <= 0 {
+			return nil
+		}
+		return split(typed)
+	case []string:
+		return typed
+	case []interface{}:
+		result := make([]string, 0, len(typed))
+		for _, item := range typed {
+			if item == nil {
+				result = append(result, "")
+				continue
+			}
+			result = append(result, fmt.Sprint(item))
+		}
+		return result
+	}
+	return []string{fmt.Sprint(raw)}
 }
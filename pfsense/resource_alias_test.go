@@ -0,0 +1,134 @@
+package pfsense
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAliasStrings(t *testing.T) {
+	fields := func(s string) []string { return strings.Fields(s) }
+
+	cases := []struct {
+		name string
+		raw  interface{}
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"empty string", "", nil},
+		{"scalar", "10.0.0.1", []string{"10.0.0.1"}},
+		{"space separated", "10.0.0.1 10.0.0.2", []string{"10.0.0.1", "10.0.0.2"}},
+		{"trailing spaces", "10.0.0.1  10.0.0.2 ", []string{"10.0.0.1", "10.0.0.2"}},
+		{"string array", []string{"a", "b"}, []string{"a", "b"}},
+		{"json array", []interface{}{"a", nil, 443.0}, []string{"a", "", "443"}},
+		{"json number", 443.0, []string{"443"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := aliasStrings(c.raw, fields); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("aliasStrings(%#v) = %#v, want %#v", c.raw, got, c.want)
+			}
+		})
+	}
+}
+
+func TestAliasEntries(t *testing.T) {
+	entry := func(value string, details string) map[string]interface{} {
+		return map[string]interface{}{"value": value, "details": details}
+	}
+
+	cases := []struct {
+		name    string
+		values  interface{}
+		details interface{}
+		want    []map[string]interface{}
+	}{
+		{"empty", "", "", []map[string]interface{}{}},
+		{"no values", nil, "web", []map[string]interface{}{}},
+		{"scalar", "10.0.0.1", "web", []map[string]interface{}{entry("10.0.0.1", "web")}},
+		{
+			"split details",
+			"10.0.0.1 10.0.0.2",
+			"web||mail",
+			[]map[string]interface{}{entry("10.0.0.1", "web"), entry("10.0.0.2", "mail")},
+		},
+		{
+			"missing detail",
+			"10.0.0.1 10.0.0.2",
+			"web",
+			[]map[string]interface{}{entry("10.0.0.1", "web"), entry("10.0.0.2", "")},
+		},
+		{
+			"empty detail in between",
+			"10.0.0.1 10.0.0.2 10.0.0.3",
+			"web||||mail",
+			[]map[string]interface{}{entry("10.0.0.1", "web"), entry("10.0.0.2", ""), entry("10.0.0.3", "mail")},
+		},
+		{
+			"details keep their spaces",
+			"10.0.0.1 ",
+			"web server ",
+			[]map[string]interface{}{entry("10.0.0.1", "web server ")},
+		},
+		{
+			"json arrays",
+			[]interface{}{"10.0.0.1", "10.0.0.2"},
+			[]interface{}{"web", nil},
+			[]map[string]interface{}{entry("10.0.0.1", "web"), entry("10.0.0.2", "")},
+		},
+		{
+			"more details than values",
+			"10.0.0.1",
+			"web||mail",
+			[]map[string]interface{}{entry("10.0.0.1", "web")},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := aliasEntries(c.values, c.details); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("aliasEntries(%#v, %#v) = %#v, want %#v", c.values, c.details, got, c.want)
+			}
+		})
+	}
+}
+
+func TestValidateAliasValue(t *testing.T) {
+	cases := []struct {
+		aliasType string
+		value     string
+		ok        bool
+	}{
+		{"host", "10.0.0.1", true},
+		{"host", "fd00::1", true},
+		{"host", "10.0.0.1-10.0.0.9", true},
+		{"host", "www.example.com", true},
+		{"host", "webservers", true},
+		{"host", "10.0.0.0/24", false},
+		{"host", "10.0.0.1-", false},
+		{"host", "8080", false},
+		{"network", "10.0.0.0/24", true},
+		{"network", "fd00::/64", true},
+		{"network", "10.0.0.1", true},
+		{"network", "10.0.0.0/33", false},
+		{"port", "443", true},
+		{"port", "8000:8080", true},
+		{"port", "webports", true},
+		{"port", "0", false},
+		{"port", "65536", false},
+		{"port", "80-90", false},
+		{"url", "https://example.com/list.txt", true},
+		{"url", "ftp://example.com/list.txt", false},
+		{"url", "webservers", false},
+		{"urltable", "http://example.com/table", true},
+		{"urltable_ports", "example.com/ports", false},
+	}
+
+	for _, c := range cases {
+		err := validateAliasValue(c.aliasType, c.value)
+		if c.ok != (err == nil) {
+			t.Errorf("validateAliasValue(%q, %q) error = %v, want ok %v", c.aliasType, c.value, err, c.ok)
+		}
+	}
+}
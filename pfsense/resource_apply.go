@@ -0,0 +1,118 @@
+package pfsense
+
+import (
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"log"
+)
+
+// pfsense_apply reloads the filter and restarts the DHCP servers once. It is
+// meant to be used with pf_deferred_apply and to depend on every rule and
+// mapping, with triggers changing whenever one of them does. Changes left
+// pending by pfSense, for a resource missing from triggers, make the next
+// plan create it again, and destroying it reloads as well.
+func resourceApply() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceApplyCreate,
+		Read:   resourceApplyRead,
+		Delete: resourceApplyDelete,
+
+		Schema: map[string]*schema.Schema{
+			"triggers": {
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+				Elem: &schema.Schema{
+					Type: schema.TypeString,
+				},
+			},
+			"filter": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  true,
+			},
+			"dhcp": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  true,
+			},
+			"dhcpv6": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  true,
+			},
+		},
+	}
+}
+
+func resourceApplyCreate(d *schema.ResourceData, meta interface{}) error {
+	err := applyPending(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(resource.UniqueId())
+	return nil
+}
+
+func resourceApplyRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	status, err := pconf.Client.GetApplyStatus()
+	if apiErr, ok := err.(*api.Error); ok && (apiErr.StatusCode == 404 || apiErr.StatusCode == 405 || apiErr.StatusCode == 501) {
+		log.Printf("[WARN] the API does not report pending changes, relying on triggers only")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if status.Pending() {
+		log.Printf("[WARN] pfSense has pending changes %v, pfsense_apply %s will run again", status.PendingSubsystems, d.Id())
+		d.SetId("")
+	}
+	return nil
+}
+
+func resourceApplyDelete(d *schema.ResourceData, meta interface{}) error {
+	err := applyPending(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId("")
+	return nil
+}
+
+func applyPending(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	// The reload only picks up the config, no section lock is needed.
+	if d.Get("filter").(bool) {
+		err := client.ApplyFilter()
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.Get("dhcp").(bool) {
+		err := client.RestartDHCP()
+		if err != nil {
+			return err
+		}
+	}
+
+	if d.Get("dhcpv6").(bool) {
+		err := client.RestartDHCPv6()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
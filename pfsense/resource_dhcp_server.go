@@ -0,0 +1,342 @@
+package pfsense
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var dhcpOptionTypes = []string{
+	"text", "string", "boolean",
+	"unsigned integer 8", "unsigned integer 16", "unsigned integer 32",
+	"signed integer 8", "signed integer 16", "signed integer 32",
+	"ip-address",
+}
+
+func resourceDhcpServer() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDhcpServerCreate,
+		Read:   resourceDhcpServerRead,
+		Update: resourceDhcpServerUpdate,
+		Delete: resourceDhcpServerDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"enable": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"range_start": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			"range_end": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			"pool": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"range_start": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPv4Address,
+						},
+						"range_end": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.IsIPv4Address,
+						},
+					},
+				},
+			},
+			"dns_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 4,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPv4Address,
+				},
+			},
+			"gateway": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			"domain": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"domain_search_list": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+			"default_lease_time": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(60),
+			},
+			"max_lease_time": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(60),
+			},
+			"deny_unknown": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "disabled",
+				ValidateFunc: validation.StringInSlice([]string{"disabled", "enabled", "class"}, false),
+			},
+			"static_arp": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"failover_peer": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv4Address,
+			},
+			"option": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"number": {
+							Type:         schema.TypeInt,
+							Required:     true,
+							ValidateFunc: validation.IntBetween(1, 254),
+						},
+						"type": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice(dhcpOptionTypes, false),
+						},
+						"value": {
+							Type:     schema.TypeString,
+							Required: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func resourceDhcpServerCreate(d *schema.ResourceData, meta interface{}) error {
+	err := resourceDhcpServerUpdate(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("interface").(string))
+	return resourceDhcpServerRead(d, meta)
+}
+
+func resourceDhcpServerRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	iface := d.Id()
+	data, err := pconf.dhcpServer(iface)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		log.Printf("[WARN] DHCP server on %s not found, removing it from state", iface)
+		d.SetId("")
+		return nil
+	}
+
+	var rangeStart, rangeEnd string
+	if data.Range != nil {
+		rangeStart, rangeEnd = data.Range.From, data.Range.To
+	}
+
+	pools := make([]interface{}, 0, len(data.Pools))
+	for _, pool := range data.Pools {
+		if pool == nil || pool.Range == nil {
+			continue
+		}
+		pools = append(pools, map[string]interface{}{
+			"range_start": pool.Range.From,
+			"range_end":   pool.Range.To,
+		})
+	}
+
+	options := make([]interface{}, 0)
+	if data.NumberOptions != nil {
+		for _, option := range data.NumberOptions.Items {
+			number, _ := strconv.Atoi(string(option.Number))
+			options = append(options, map[string]interface{}{
+				"number": number,
+				"type":   option.Type,
+				"value":  decodeDhcpOptionValue(option.Value),
+			})
+		}
+	}
+
+	values := map[string]interface{}{
+		"interface":          iface,
+		"enable":             bool(data.Enable),
+		"range_start":        rangeStart,
+		"range_end":          rangeEnd,
+		"pool":               pools,
+		"dns_servers":        []string(data.DnsServers),
+		"gateway":            data.Gateway,
+		"domain":             data.Domain,
+		"domain_search_list": splitDomainSearchList(data.DomainSearchList),
+		"default_lease_time": leaseTime(data.DefaultLeaseTime),
+		"max_lease_time":     leaseTime(data.MaxLeaseTime),
+		"deny_unknown":       dhcpDenyUnknown(data.DenyUnknown),
+		"static_arp":         bool(data.StaticArp),
+		"failover_peer":      data.FailoverPeerIp,
+		"option":             options,
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceDhcpServerUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	request := dhcpServerRequest(d)
+	request["apply"] = pconf.applyNow()
+
+	return pconf.write(dhcpSection(d.Get("interface").(string)), func() error {
+		return client.UpdateDHCPServer(request)
+	})
+}
+
+// Deleting the resource only disables the server, the settings of the
+// interface stay in the config. pfsense_dhcpv6_server does the same.
+func resourceDhcpServerDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface := d.Id()
+
+	return pconf.write(dhcpSection(iface), func() error {
+		err := client.UpdateDHCPServer(api.Request{
+			"interface": iface,
+			"enable":    false,
+			"apply":     pconf.applyDeleteNow(),
+		})
+		if api.IsNotFound(err) {
+			log.Printf("[WARN] DHCP server on %s already deleted", iface)
+			return nil
+		}
+		return err
+	})
+}
+
+func dhcpServerRequest(d *schema.ResourceData) api.Request {
+	request := api.Request{
+		"interface":        d.Get("interface").(string),
+		"enable":           d.Get("enable").(bool),
+		"range_from":       d.Get("range_start").(string),
+		"range_to":         d.Get("range_end").(string),
+		"dnsserver":        stringList(d.Get("dns_servers").([]interface{})),
+		"gateway":          d.Get("gateway").(string),
+		"domain":           d.Get("domain").(string),
+		"domainsearchlist": strings.Join(stringList(d.Get("domain_search_list").([]interface{})), ";"),
+		"staticarp":        d.Get("static_arp").(bool),
+		"failover_peerip":  d.Get("failover_peer").(string),
+		"defaultleasetime": "",
+		"maxleasetime":     "",
+	}
+
+	if leaseTime, ok := d.GetOk("default_lease_time"); ok {
+		request["defaultleasetime"] = leaseTime
+	}
+	if leaseTime, ok := d.GetOk("max_lease_time"); ok {
+		request["maxleasetime"] = leaseTime
+	}
+
+	switch d.Get("deny_unknown").(string) {
+	case "enabled":
+		request["denyunknown"] = "enabled"
+	case "class":
+		request["denyunknown"] = "class"
+	default:
+		request["denyunknown"] = false
+	}
+
+	pools := make([]interface{}, 0)
+	for _, raw := range d.Get("pool").([]interface{}) {
+		pool := raw.(map[string]interface{})
+		pools = append(pools, map[string]interface{}{
+			"range": map[string]interface{}{
+				"from": pool["range_start"],
+				"to":   pool["range_end"],
+			},
+		})
+	}
+	request["pool"] = pools
+
+	options := make([]interface{}, 0)
+	for _, raw := range d.Get("option").([]interface{}) {
+		option := raw.(map[string]interface{})
+		options = append(options, map[string]interface{}{
+			"number": fmt.Sprint(option["number"]),
+			"type":   option["type"],
+			"value":  base64.StdEncoding.EncodeToString([]byte(option["value"].(string))),
+		})
+	}
+	request["numberoptions"] = map[string]interface{}{"item": options}
+
+	return request
+}
+
+// pfSense 2.5 and later store the values of number options base64 encoded,
+// older versions as plain text, which is kept as is when it does not decode.
+func decodeDhcpOptionValue(value string) string {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || !utf8.Valid(decoded) {
+		return value
+	}
+	return string(decoded)
+}
+
+// Older pfSense versions store deny unknown clients as an empty element,
+// newer ones as "enabled" or "class".
+func dhcpDenyUnknown(value *api.FlexString) string {
+	switch {
+	case value == nil || *value == "false":
+		return "disabled"
+	case *value == "class":
+		return "class"
+	}
+	return "enabled"
+}
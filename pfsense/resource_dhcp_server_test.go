@@ -0,0 +1,52 @@
+package pfsense
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+)
+
+func TestDecodeDhcpOptionValue(t *testing.T) {
+	cases := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{base64.StdEncoding.EncodeToString([]byte("pxelinux.0")), "pxelinux.0"},
+		{base64.StdEncoding.EncodeToString([]byte(`"quoted value"`)), `"quoted value"`},
+		{"pxelinux.0", "pxelinux.0"},
+		{"10.0.0.1", "10.0.0.1"},
+		{"test", "test"},
+	}
+
+	for _, c := range cases {
+		if got := decodeDhcpOptionValue(c.value); got != c.want {
+			t.Errorf("decodeDhcpOptionValue(%q) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
+
+func TestDhcpDenyUnknown(t *testing.T) {
+	value := func(s string) *api.FlexString {
+		v := api.FlexString(s)
+		return &v
+	}
+
+	cases := []struct {
+		value *api.FlexString
+		want  string
+	}{
+		{nil, "disabled"},
+		{value("false"), "disabled"},
+		{value(""), "enabled"},
+		{value("enabled"), "enabled"},
+		{value("class"), "class"},
+	}
+
+	for _, c := range cases {
+		if got := dhcpDenyUnknown(c.value); got != c.want {
+			t.Errorf("dhcpDenyUnknown(%v) = %q, want %q", c.value, got, c.want)
+		}
+	}
+}
@@ -2,11 +2,11 @@ package pfsense
 
 import (
 	"fmt"
-	"github.com/go-resty/resty/v2"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
-	"regexp"
-	"time"
+	"log"
+	"strings"
 )
 
 func resourceDhcpStaticMapping() *schema.Resource {
@@ -18,32 +18,100 @@ func resourceDhcpStaticMapping() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceDhcpStaticMappingCustomizeDiff,
 
 		Schema: map[string]*schema.Schema{
 			"interface": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
 				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
 			"mac": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"mac", "client_identifier"},
 				ValidateFunc: validation.IsMACAddress,
 			},
 			"ipaddr": {
-				Type:     schema.TypeString,
-				Required: true,
+				Type:         schema.TypeString,
+				Required:     true,
 				ValidateFunc: validation.IsIPAddress,
 			},
 			"client_identifier": {
-				Type:     schema.TypeString,
-				Optional: true,
-				ValidateFunc:  validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				Type:         schema.TypeString,
+				Optional:     true,
+				AtLeastOneOf: []string{"mac", "client_identifier"},
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
 			"hostname": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"description": {
 				Type:     schema.TypeString,
 				Optional: true,
-				ValidateFunc:  validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"gateway": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPAddress,
+			},
+			"dns_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 4,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPAddress,
+				},
+			},
+			"domain": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"domain_search_list": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+			"ntp_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 3,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+			"wins_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 2,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPAddress,
+				},
+			},
+			"tftp_filename": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"root_path": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"arp_table_static_entry": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
 			},
 		},
 	}
@@ -51,127 +119,93 @@ func resourceDhcpStaticMapping() *schema.Resource {
 
 func resourceDhcpStaticMappingCreate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-
 	client := pconf.Client
-	lock := pconf.Mutex
 
-	fetchRequest := map[string]string{
-		"interface": d.Get("interface").(string),
-		"mac": d.Get("mac").(string),
-	}
-
-	lock.Lock()
-	data, err := fetchDHCPRow(client, fetchRequest)
-	if err != nil {
-		lock.Unlock()
-		return err
-	}
+	iface := d.Get("interface").(string)
+	key := dhcpMappingKeyFromConfig(d)
 
-	if data != nil {
-		lock.Unlock()
-		return fmt.Errorf("mapping for this mac already exists! data: %s", data)
-	}
+	err := pconf.write(dhcpSection(iface), func() error {
+		data, err := fetchDHCPRow(client, iface, key)
+		if err != nil {
+			return err
+		}
 
-	var request = map[string]interface{}{
-		"interface": d.Get("interface"),
-		"mac": d.Get("mac"),
-		"ipaddr": d.Get("ipaddr"),
-	}
+		if data != nil {
+			return fmt.Errorf("mapping for this %s already exists! data: %v", key, data)
+		}
 
-	var cid = d.Get("client_identifier")
-	if cid != nil {
-		request["cid"] = cid
-	}
+		var request = dhcpStaticMappingRequest(d)
+		request["interface"] = iface
+		request["apply"] = pconf.applyNow()
 
-	var hostname = d.Get("hostname")
-	if hostname != nil {
-		request["hostname"] = hostname
-	}
+		err = client.CreateDHCPStaticMapping(request)
+		if err != nil {
+			return err
+		}
 
-	resp, err := client.R().
-		SetBody(request).
-		Post(PFSenseApiUri.DHCPStaticMapping)
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			var err1 error
+			data, err1 = fetchDHCPRow(client, iface, key)
+			return data != nil, err1
+		})
+		if err != nil {
+			return fmt.Errorf("mapping for this %s do not exists! interface: %s, error: %s", key, iface, err)
+		}
 
+		d.SetId(dhcpResourceId(iface, key.of(data)))
+		return nil
+	})
 	if err != nil {
-		lock.Unlock()
 		return err
 	}
 
-	if resp.StatusCode() != 200 {
-		lock.Unlock()
-		return fmt.Errorf("invalid response code on create: %d", resp.StatusCode())
-	}
-
-	time.Sleep(100 * time.Millisecond)
-
-	data, err1 := fetchDHCPRow(client, fetchRequest)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("mapping for this mac do not exists! data: %s", data)
-	}
-
-	d.SetId(dhcpResourceId(request["interface"].(string), data.Mac))
-	lock.Unlock()
-
-	err = resourceDhcpStaticMappingRead(d, meta)
-	return err
+	return resourceDhcpStaticMappingRead(d, meta)
 }
 
 func resourceDhcpStaticMappingRead(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-	lock := pconf.Mutex
-	client := pconf.Client
 
-	lock.Lock()
-	iface, mac, err := parseDhcpResourceId(d.Id())
+	iface, key, err := parseDhcpResourceId(d.Id())
 	if err != nil {
 		d.SetId("")
-		lock.Unlock()
 		return err
 	}
 
-	fetchRequest := map[string]string{
-		"interface": iface,
-		"mac": mac,
-	}
-
-	data, err1 := fetchDHCPRow(client, fetchRequest)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
+	data, err := pconf.dhcpStaticMapping(iface, key)
+	if err != nil {
+		return err
 	}
 	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("mapping for this id do not exists! request: %s, data: %s", fetchRequest, data)
-	}
-
-	d.SetId(dhcpResourceId(iface, data.Mac))
-
-	err2 := d.Set("interface", iface)
-	if err2 != nil {
-		return err2
-	}
-	err2 = d.Set("ipaddr", data.Ipaddr)
-	if err2 != nil {
-		return err2
-	}
-	err2 = d.Set("hostname", data.Hostname)
-	if err2 != nil {
-		return err2
-	}
-	err2 = d.Set("client_identifier", data.Cid)
-	if err2 != nil {
-		return err2
-	}
-	err2 = d.Set("mac", data.Mac)
-	if err2 != nil {
-		return err2
+		log.Printf("[WARN] DHCP static mapping %s on %s not found, removing it from state", key, iface)
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(dhcpResourceId(iface, key.of(data)))
+
+	values := map[string]interface{}{
+		"interface":              iface,
+		"mac":                    data.Mac,
+		"ipaddr":                 data.Ipaddr,
+		"hostname":               data.Hostname,
+		"client_identifier":      data.Cid,
+		"description":            data.Description,
+		"gateway":                data.Gateway,
+		"dns_servers":            []string(data.DnsServers),
+		"domain":                 data.Domain,
+		"domain_search_list":     splitDomainSearchList(data.DomainSearchList),
+		"ntp_servers":            []string(data.NtpServers),
+		"wins_servers":           []string(data.WinsServers),
+		"tftp_filename":          data.Filename,
+		"root_path":              data.RootPath,
+		"arp_table_static_entry": bool(data.ArpTableStaticEntry),
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
 	}
-	lock.Unlock()
 
 	return nil
 }
@@ -179,81 +213,99 @@ func resourceDhcpStaticMappingRead(d *schema.ResourceData, meta interface{}) err
 func resourceDhcpStaticMappingDelete(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
 	client := pconf.Client
-	lock := pconf.Mutex
 
-	iface, mac, err := parseDhcpResourceId(d.Id())
+	iface, key, err := parseDhcpResourceId(d.Id())
 	if err != nil {
 		d.SetId("")
 		return err
 	}
-	fetchRequest := map[string]string{
-		"interface": iface,
-		"mac": mac,
-	}
-
-	lock.Lock()
-	data, err1 := fetchDHCPRow(client, fetchRequest)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("mapping for this id do not exists! request: %s, data: %s", fetchRequest, data)
-	}
-
-	var request = map[string]interface{}{
-		"id": data.Id,
-		"interface": iface,
-	}
 
-	resp, err := client.R().
-		SetBody(request).
-		Delete(PFSenseApiUri.DHCPStaticMapping)
+	return pconf.write(dhcpSection(iface), func() error {
+		data, err := fetchDHCPRow(client, iface, key)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			log.Printf("[WARN] DHCP static mapping %s on %s already deleted", key, iface)
+			return nil
+		}
 
-	lock.Unlock()
-	if err != nil {
+		err = client.DeleteDHCPStaticMapping(iface, data.Id, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			return nil
+		}
 		return err
-	}
-
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code on delete: %d", resp.StatusCode())
-	}
-
-	return nil
+	})
 }
 
-
 func resourceDhcpStaticMappingUpdate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
 	client := pconf.Client
-	lock := pconf.Mutex
-	iface, mac, err := parseDhcpResourceId(d.Id())
+
+	iface, key, err := parseDhcpResourceId(d.Id())
 	if err != nil {
 		d.SetId("")
 		return err
 	}
 
-	fetchRequest := map[string]string{
-		"interface": iface,
-		"mac": mac,
-	}
+	return pconf.write(dhcpSection(iface), func() error {
+		data, err := fetchDHCPRow(client, iface, key)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return fmt.Errorf("mapping for this id do not exists! interface: %s, %s", iface, key)
+		}
 
-	lock.Lock()
-	data, err1 := fetchDHCPRow(client, fetchRequest)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("mapping for this id do not exists! request: %s, data: %s", fetchRequest, data)
-	}
+		newKey := dhcpMappingKeyFromConfig(d)
+		if newKey != key {
+			existing, err := fetchDHCPRow(client, iface, newKey)
+			if err != nil {
+				return err
+			}
+			if existing != nil && existing.Id != data.Id {
+				return fmt.Errorf("mapping for this %s already exists! data: %v", newKey, existing)
+			}
+		}
+
+		var request = dhcpStaticMappingRequest(d)
+		request["id"] = data.Id
+		request["interface"] = iface
+		request["apply"] = pconf.applyNow()
 
-	var request = map[string]interface{}{
-		"id": data.Id,
-		"interface": iface,
-		"mac": d.Get("mac"),
+		err = client.UpdateDHCPStaticMapping(request)
+		if err != nil {
+			return err
+		}
+
+		// The mac or client identifier the mapping is found by may have
+		// changed with the update.
+		d.SetId(dhcpResourceId(iface, newKey))
+		return nil
+	})
+}
+
+// resourceDhcpStaticMappingCustomizeDiff checks ipaddr against the subnet of
+// the interface and the dynamic pools of its DHCP server.
+func resourceDhcpStaticMappingCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	return checkStaticAddress(d, meta, "ipaddr", false, func(pconf *providerConfiguration, iface string) ([]*api.DHCPRange, error) {
+		server, err := pconf.dhcpServer(iface)
+		if err != nil || server == nil {
+			return nil, err
+		}
+		ranges := []*api.DHCPRange{server.Range}
+		for _, pool := range server.Pools {
+			if pool != nil {
+				ranges = append(ranges, pool.Range)
+			}
+		}
+		return ranges, nil
+	})
+}
+
+func dhcpStaticMappingRequest(d *schema.ResourceData) api.Request {
+	var request = api.Request{
+		"mac":    d.Get("mac"),
 		"ipaddr": d.Get("ipaddr"),
 	}
 
@@ -267,89 +319,91 @@ func resourceDhcpStaticMappingUpdate(d *schema.ResourceData, meta interface{}) e
 		request["hostname"] = hostname
 	}
 
-	resp, err := client.R().
-		SetBody(request).
-		Put(PFSenseApiUri.DHCPStaticMapping)
+	request["descr"] = d.Get("description").(string)
+	request["gateway"] = d.Get("gateway").(string)
+	request["dnsserver"] = stringList(d.Get("dns_servers").([]interface{}))
+	request["domain"] = d.Get("domain").(string)
+	request["domainsearchlist"] = strings.Join(stringList(d.Get("domain_search_list").([]interface{})), ";")
+	request["ntpserver"] = stringList(d.Get("ntp_servers").([]interface{}))
+	request["winsserver"] = stringList(d.Get("wins_servers").([]interface{}))
+	request["filename"] = d.Get("tftp_filename").(string)
+	request["rootpath"] = d.Get("root_path").(string)
+	request["arp_table_static_entry"] = d.Get("arp_table_static_entry").(bool)
 
-	lock.Unlock()
-	if err != nil {
-		return err
-	}
-
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code: %d", resp.StatusCode())
-	}
-
-	return nil
+	return request
 }
 
-func dhcpResourceId(iface string, mac string) string {
-	return fmt.Sprintf("%s/%s", iface, mac)
+// dhcpMappingKey identifies a static mapping by its mac or, for hosts that
+// rotate their mac, by its client identifier alone.
+type dhcpMappingKey struct {
+	Mac string
+	Cid string
 }
 
-var rxRsId = regexp.MustCompile("([^/]+)/([^/]+)")
-
-func parseDhcpResourceId(resId string) (iface string, mac string, err error) {
-	if !rxRsId.MatchString(resId) {
-		return "", "", fmt.Errorf("invalid resource format: %s. must be itnreface/mac", resId)
+func dhcpMappingKeyFromConfig(d *schema.ResourceData) dhcpMappingKey {
+	if mac := d.Get("mac").(string); len(mac) > 0 {
+		return dhcpMappingKey{Mac: mac}
 	}
-	idMatch := rxRsId.FindStringSubmatch(resId)
-	iface = idMatch[1]
-	mac = idMatch[2]
-	return
+	return dhcpMappingKey{Cid: d.Get("client_identifier").(string)}
 }
 
-func fetchDHCPRow(client *resty.Client, request map[string]string) (*ReadDHCPStaticMapping, error) {
-	resp, err := client.R().
-		SetQueryParams(request).
-		SetResult(&ReadDHCPStaticMappingMapResponse{}).
-		ForceContentType("application/json").
-		Get(PFSenseApiUri.DHCPStaticMapping)
-
-	if err != nil {
-		resp, err = client.R().
-			SetQueryParams(request).
-			SetResult(&ReadDHCPStaticMappingArrayResponse{}).
-			ForceContentType("application/json").
-			Get(PFSenseApiUri.DHCPStaticMapping)
-
-		if err != nil {
-			return nil, err
-		}
-		return nil, nil
+func (k dhcpMappingKey) matches(mapping *api.DHCPStaticMapping) bool {
+	if len(k.Mac) > 0 {
+		return strings.EqualFold(mapping.Mac, k.Mac)
 	}
+	return len(k.Cid) > 0 && mapping.Cid == k.Cid
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("invalid response code on fetch: %d", resp.StatusCode())
+// of returns the key as stored in mapping, pfSense may change the case of
+// the mac.
+func (k dhcpMappingKey) of(mapping *api.DHCPStaticMapping) dhcpMappingKey {
+	if len(k.Mac) > 0 {
+		return dhcpMappingKey{Mac: mapping.Mac}
 	}
+	return k
+}
 
-	var result = resp.Result().(*ReadDHCPStaticMappingMapResponse)
-
-	if len(result.Data) > 1 {
-		return nil, fmt.Errorf("failed to retrieve id of created mapping! more then one result found or none. request: %s, data: %s", request, result)
+func (k dhcpMappingKey) String() string {
+	if len(k.Mac) > 0 {
+		return "mac " + k.Mac
 	}
+	return "client identifier " + k.Cid
+}
 
-	for k := range result.Data {
-		return result.Data[k], nil
-	}
+const dhcpCidPrefix = "cid:"
 
-	return nil, nil
+func dhcpResourceId(iface string, key dhcpMappingKey) string {
+	if len(key.Mac) > 0 {
+		return fmt.Sprintf("%s/%s", iface, key.Mac)
+	}
+	return fmt.Sprintf("%s/%s%s", iface, dhcpCidPrefix, key.Cid)
 }
 
-type ReadDHCPStaticMappingArrayResponse struct {
-	ApiBaseResponse
-	Data []*ReadDHCPStaticMapping `json:"data"`
+func parseDhcpResourceId(resId string) (iface string, key dhcpMappingKey, err error) {
+	parts := strings.SplitN(resId, "/", 2)
+	if len(parts) != 2 || len(parts[0]) <= 0 || len(parts[1]) <= 0 || parts[1] == dhcpCidPrefix {
+		return "", key, fmt.Errorf("invalid resource format: %s. must be interface/mac or interface/%s<client identifier>", resId, dhcpCidPrefix)
+	}
+	iface = parts[0]
+	if strings.HasPrefix(parts[1], dhcpCidPrefix) {
+		key.Cid = strings.TrimPrefix(parts[1], dhcpCidPrefix)
+	} else {
+		key.Mac = parts[1]
+	}
+	return
 }
 
-type ReadDHCPStaticMappingMapResponse struct {
-	ApiBaseResponse
-	Data map[string]*ReadDHCPStaticMapping `json:"data"`
-}
+func fetchDHCPRow(client *api.Client, iface string, key dhcpMappingKey) (*api.DHCPStaticMapping, error) {
+	result, err := client.ListDHCPStaticMappings(map[string]string{"interface": iface})
+	if err != nil {
+		return nil, err
+	}
 
-type ReadDHCPStaticMapping struct {
-	Id int `json:"id"`
-	Mac string `json:"mac"`
-	Cid string `json:"cid"`
-	Ipaddr string `json:"ipaddr"`
-	Hostname string `json:"hostname"`
+	i, err := findStaticMapping(key.String(), iface, len(result), func(i int) bool {
+		return key.matches(result[i])
+	})
+	if err != nil || i < 0 {
+		return nil, err
+	}
+	return result[i], nil
 }
@@ -0,0 +1,43 @@
+package pfsense
+
+import (
+	"testing"
+)
+
+func TestParseDhcpResourceId(t *testing.T) {
+	cases := []struct {
+		id    string
+		iface string
+		key   dhcpMappingKey
+		ok    bool
+	}{
+		{"lan/00:11:22:33:44:55", "lan", dhcpMappingKey{Mac: "00:11:22:33:44:55"}, true},
+		{"opt1/cid:host-1", "opt1", dhcpMappingKey{Cid: "host-1"}, true},
+		{"lan/cid:a/b", "lan", dhcpMappingKey{Cid: "a/b"}, true},
+		{"lan/cid:", "", dhcpMappingKey{}, false},
+		{"lan/", "", dhcpMappingKey{}, false},
+		{"/00:11:22:33:44:55", "", dhcpMappingKey{}, false},
+		{"00:11:22:33:44:55", "", dhcpMappingKey{}, false},
+		{"", "", dhcpMappingKey{}, false},
+	}
+
+	for _, c := range cases {
+		iface, key, err := parseDhcpResourceId(c.id)
+		if !c.ok {
+			if err == nil {
+				t.Errorf("parseDhcpResourceId(%q) = %q, %v, want an error", c.id, iface, key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDhcpResourceId(%q) failed: %v", c.id, err)
+			continue
+		}
+		if iface != c.iface || key != c.key {
+			t.Errorf("parseDhcpResourceId(%q) = %q, %v, want %q, %v", c.id, iface, key, c.iface, c.key)
+		}
+		if back := dhcpResourceId(iface, key); back != c.id {
+			t.Errorf("dhcpResourceId(%q, %v) = %q, want %q", iface, key, back, c.id)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package pfsense
+
+import (
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"strconv"
+	"strings"
+)
+
+func resourceDhcpv6Server() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDhcpv6ServerCreate,
+		Read:   resourceDhcpv6ServerRead,
+		Update: resourceDhcpv6ServerUpdate,
+		Delete: resourceDhcpv6ServerDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"enable": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"range_start": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv6Address,
+			},
+			"range_end": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv6Address,
+			},
+			"prefix_range_start": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv6Address,
+			},
+			"prefix_range_end": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv6Address,
+			},
+			"prefix_length": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(48, 64),
+			},
+			"dns_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 4,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPv6Address,
+				},
+			},
+			"domain": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"domain_search_list": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+			"default_lease_time": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(60),
+			},
+			"max_lease_time": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(60),
+			},
+		},
+	}
+}
+
+func resourceDhcpv6ServerCreate(d *schema.ResourceData, meta interface{}) error {
+	err := resourceDhcpv6ServerUpdate(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("interface").(string))
+	return resourceDhcpv6ServerRead(d, meta)
+}
+
+func resourceDhcpv6ServerRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	iface := d.Id()
+	data, err := pconf.dhcpv6Server(iface)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		log.Printf("[WARN] DHCPv6 server on %s not found, removing it from state", iface)
+		d.SetId("")
+		return nil
+	}
+
+	var rangeStart, rangeEnd string
+	if data.Range != nil {
+		rangeStart, rangeEnd = data.Range.From, data.Range.To
+	}
+
+	var prefixStart, prefixEnd string
+	var prefixLength int
+	if data.PrefixRange != nil {
+		prefixStart, prefixEnd = data.PrefixRange.From, data.PrefixRange.To
+		prefixLength, _ = strconv.Atoi(string(data.PrefixRange.PrefixLength))
+	}
+
+	values := map[string]interface{}{
+		"interface":          iface,
+		"enable":             bool(data.Enable),
+		"range_start":        rangeStart,
+		"range_end":          rangeEnd,
+		"prefix_range_start": prefixStart,
+		"prefix_range_end":   prefixEnd,
+		"prefix_length":      prefixLength,
+		"dns_servers":        []string(data.DnsServers),
+		"domain":             data.Domain,
+		"domain_search_list": splitDomainSearchList(data.DomainSearchList),
+		"default_lease_time": leaseTime(data.DefaultLeaseTime),
+		"max_lease_time":     leaseTime(data.MaxLeaseTime),
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceDhcpv6ServerUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	request := dhcpv6ServerRequest(d)
+	request["apply"] = pconf.applyNow()
+
+	return pconf.write(dhcpv6Section(d.Get("interface").(string)), func() error {
+		return client.UpdateDHCPv6Server(request)
+	})
+}
+
+func resourceDhcpv6ServerDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface := d.Id()
+
+	return pconf.write(dhcpv6Section(iface), func() error {
+		err := client.UpdateDHCPv6Server(api.Request{
+			"interface": iface,
+			"enable":    false,
+			"apply":     pconf.applyDeleteNow(),
+		})
+		if api.IsNotFound(err) {
+			log.Printf("[WARN] DHCPv6 server on %s already deleted", iface)
+			return nil
+		}
+		return err
+	})
+}
+
+func dhcpv6ServerRequest(d *schema.ResourceData) api.Request {
+	request := api.Request{
+		"interface":          d.Get("interface").(string),
+		"enable":             d.Get("enable").(bool),
+		"range_from":         d.Get("range_start").(string),
+		"range_to":           d.Get("range_end").(string),
+		"prefixrange_from":   d.Get("prefix_range_start").(string),
+		"prefixrange_to":     d.Get("prefix_range_end").(string),
+		"prefixrange_length": "",
+		"dnsserver":          stringList(d.Get("dns_servers").([]interface{})),
+		"domain":             d.Get("domain").(string),
+		"domainsearchlist":   strings.Join(stringList(d.Get("domain_search_list").([]interface{})), ";"),
+		"defaultleasetime":   "",
+		"maxleasetime":       "",
+	}
+
+	if length, ok := d.GetOk("prefix_length"); ok {
+		request["prefixrange_length"] = length
+	}
+	if leaseTime, ok := d.GetOk("default_lease_time"); ok {
+		request["defaultleasetime"] = leaseTime
+	}
+	if leaseTime, ok := d.GetOk("max_lease_time"); ok {
+		request["maxleasetime"] = leaseTime
+	}
+
+	return request
+}
@@ -0,0 +1,258 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var duidRegex = regexp.MustCompile("^([0-9a-fA-F]{2}:)+[0-9a-fA-F]{2}$")
+
+func resourceDhcpv6StaticMapping() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceDhcpv6StaticMappingCreate,
+		Read:   resourceDhcpv6StaticMappingRead,
+		Update: resourceDhcpv6StaticMappingUpdate,
+		Delete: resourceDhcpv6StaticMappingDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceDhcpv6StaticMappingCustomizeDiff,
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"duid": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringMatch(duidRegex, "must be hex bytes separated by colons"),
+			},
+			"ipaddrv6": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.IsIPv6Address,
+			},
+			"hostname": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceDhcpv6StaticMappingCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface := d.Get("interface").(string)
+	duid := d.Get("duid").(string)
+
+	err := pconf.write(dhcpv6Section(iface), func() error {
+		data, err := fetchDHCPv6Row(client, iface, duid)
+		if err != nil {
+			return err
+		}
+
+		if data != nil {
+			return fmt.Errorf("mapping for this duid already exists! data: %v", data)
+		}
+
+		var request = dhcpv6StaticMappingRequest(d)
+		request["interface"] = iface
+		request["apply"] = pconf.applyNow()
+
+		err = client.CreateDHCPv6StaticMapping(request)
+		if err != nil {
+			return err
+		}
+
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			var err1 error
+			data, err1 = fetchDHCPv6Row(client, iface, duid)
+			return data != nil, err1
+		})
+		if err != nil {
+			return fmt.Errorf("mapping for this duid do not exists! interface: %s, duid: %s, error: %s", iface, duid, err)
+		}
+
+		d.SetId(dhcpv6ResourceId(iface, data.Duid))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceDhcpv6StaticMappingRead(d, meta)
+}
+
+func resourceDhcpv6StaticMappingRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	iface, duid, err := parseDhcpv6ResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	data, err := pconf.dhcpv6StaticMapping(iface, duid)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		log.Printf("[WARN] DHCPv6 static mapping %s on %s not found, removing it from state", duid, iface)
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(dhcpv6ResourceId(iface, data.Duid))
+
+	values := map[string]interface{}{
+		"interface":   iface,
+		"duid":        data.Duid,
+		"ipaddrv6":    data.Ipaddrv6,
+		"hostname":    data.Hostname,
+		"description": data.Description,
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceDhcpv6StaticMappingDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface, duid, err := parseDhcpv6ResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return pconf.write(dhcpv6Section(iface), func() error {
+		data, err := fetchDHCPv6Row(client, iface, duid)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			log.Printf("[WARN] DHCPv6 static mapping %s on %s already deleted", duid, iface)
+			return nil
+		}
+
+		err = client.DeleteDHCPv6StaticMapping(iface, data.Id, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+func resourceDhcpv6StaticMappingUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface, duid, err := parseDhcpv6ResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return pconf.write(dhcpv6Section(iface), func() error {
+		data, err := fetchDHCPv6Row(client, iface, duid)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			return fmt.Errorf("mapping for this id do not exists! interface: %s, duid: %s", iface, duid)
+		}
+
+		newDuid := d.Get("duid").(string)
+		if !strings.EqualFold(newDuid, duid) {
+			existing, err := fetchDHCPv6Row(client, iface, newDuid)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return fmt.Errorf("mapping for this duid already exists! data: %v", existing)
+			}
+		}
+
+		var request = dhcpv6StaticMappingRequest(d)
+		request["id"] = data.Id
+		request["interface"] = iface
+		request["apply"] = pconf.applyNow()
+
+		err = client.UpdateDHCPv6StaticMapping(request)
+		if err != nil {
+			return err
+		}
+
+		d.SetId(dhcpv6ResourceId(iface, newDuid))
+		return nil
+	})
+}
+
+// resourceDhcpv6StaticMappingCustomizeDiff checks ipaddrv6 against the subnet
+// of the interface and the range of its DHCPv6 server.
+func resourceDhcpv6StaticMappingCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	return checkStaticAddress(d, meta, "ipaddrv6", true, func(pconf *providerConfiguration, iface string) ([]*api.DHCPRange, error) {
+		server, err := pconf.dhcpv6Server(iface)
+		if err != nil || server == nil {
+			return nil, err
+		}
+		return []*api.DHCPRange{server.Range}, nil
+	})
+}
+
+func dhcpv6StaticMappingRequest(d *schema.ResourceData) api.Request {
+	return api.Request{
+		"duid":     d.Get("duid").(string),
+		"ipaddrv6": d.Get("ipaddrv6").(string),
+		"hostname": d.Get("hostname").(string),
+		"descr":    d.Get("description").(string),
+	}
+}
+
+func dhcpv6ResourceId(iface string, duid string) string {
+	return fmt.Sprintf("%s/%s", iface, duid)
+}
+
+func parseDhcpv6ResourceId(resId string) (iface string, duid string, err error) {
+	parts := strings.SplitN(resId, "/", 2)
+	if len(parts) != 2 || len(parts[0]) <= 0 || !duidRegex.MatchString(parts[1]) {
+		return "", "", fmt.Errorf("invalid resource format: %s. must be interface/duid", resId)
+	}
+	return parts[0], parts[1], nil
+}
+
+func fetchDHCPv6Row(client *api.Client, iface string, duid string) (*api.DHCPv6StaticMapping, error) {
+	result, err := client.ListDHCPv6StaticMappings(map[string]string{"interface": iface})
+	if err != nil {
+		return nil, err
+	}
+
+	i, err := findStaticMapping("duid "+duid, iface, len(result), func(i int) bool {
+		return strings.EqualFold(result[i].Duid, duid)
+	})
+	if err != nil || i < 0 {
+		return nil, err
+	}
+	return result[i], nil
+}
@@ -0,0 +1,365 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"regexp"
+)
+
+func resourceFirewallRule() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceFirewallRuleCreate,
+		Read:   resourceFirewallRuleRead,
+		Update: resourceFirewallRuleUpdate,
+		Delete: resourceFirewallRuleDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"floating": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				ForceNew: true,
+				Default:  false,
+			},
+			"direction": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"in", "out", "any"}, false),
+			},
+			"action": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"pass", "block", "reject"}, false),
+			},
+			"ip_protocol": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "inet",
+				ValidateFunc: validation.StringInSlice([]string{"inet", "inet6", "inet46"}, false),
+			},
+			"protocol": {
+				Type:     schema.TypeString,
+				Optional: true,
+				Default:  "any",
+				ValidateFunc: validation.StringInSlice([]string{
+					"any", "tcp", "udp", "tcp/udp", "icmp", "esp", "ah", "gre", "ipv6", "igmp", "pim", "ospf", "carp", "pfsync",
+				}, false),
+			},
+			"src": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"src_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"srcport": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"dst": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"dst_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"dstport": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"gateway": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"schedule": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"state_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "keep state",
+				ValidateFunc: validation.StringInSlice([]string{"keep state", "sloppy state", "synproxy state", "none"}, false),
+			},
+			"log": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"disabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+		},
+	}
+}
+
+func resourceFirewallRuleCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	request := firewallRuleRequest(d)
+	request["apply"] = pconf.applyNow()
+
+	err := pconf.write(sectionFilter, func() error {
+		rule, err := client.CreateFirewallRule(request)
+		if err != nil {
+			return err
+		}
+
+		if len(rule.Tracker) <= 0 {
+			return fmt.Errorf("failed to find created firewall rule! no tracker returned, data: %v", rule)
+		}
+
+		tracker := string(rule.Tracker)
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			data, err1 := client.ListFirewallRules()
+			if err1 != nil {
+				return false, err1
+			}
+			return findFirewallRule(data, tracker) != nil, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find created firewall rule! tracker: %s, error: %s", tracker, err)
+		}
+
+		d.SetId(firewallRuleResourceId(tracker))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceFirewallRuleRead(d, meta)
+}
+
+func resourceFirewallRuleRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	tracker, err := parseFirewallRuleResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	rule, err := pconf.firewallRule(tracker)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		log.Printf("[WARN] firewall rule %s not found, removing it from state", tracker)
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(firewallRuleResourceId(tracker))
+
+	values := map[string]interface{}{
+		"interface":   rule.Interface,
+		"floating":    bool(rule.Floating),
+		"direction":   rule.Direction,
+		"action":      rule.Type,
+		"ip_protocol": rule.IpProtocol,
+		"protocol":    firewallRuleProtocol(rule.Protocol),
+		"src":         rule.Source.AddressString(),
+		"src_not":     rule.Source.Inverted(),
+		"srcport":     rule.Source.PortValue(),
+		"dst":         rule.Destination.AddressString(),
+		"dst_not":     rule.Destination.Inverted(),
+		"dstport":     rule.Destination.PortValue(),
+		"gateway":     rule.Gateway,
+		"schedule":    rule.Schedule,
+		"state_type":  rule.StateType,
+		"log":         bool(rule.Log),
+		"disabled":    bool(rule.Disabled),
+		"description": rule.Description,
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceFirewallRuleUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseFirewallRuleResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	err = pconf.write(sectionFilter, func() error {
+		request := firewallRuleRequest(d)
+		request["tracker"] = tracker
+		request["apply"] = pconf.applyNow()
+
+		return client.UpdateFirewallRule(request)
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceFirewallRuleRead(d, meta)
+}
+
+func resourceFirewallRuleDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseFirewallRuleResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return pconf.write(sectionFilter, func() error {
+		err := client.DeleteFirewallRule(tracker, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			log.Printf("[WARN] firewall rule %s already deleted", tracker)
+			return nil
+		}
+		return err
+	})
+}
+
+func firewallRuleRequest(d *schema.ResourceData) api.Request {
+	request := api.Request{
+		"interface":  d.Get("interface").(string),
+		"type":       d.Get("action").(string),
+		"ipprotocol": d.Get("ip_protocol").(string),
+		"protocol":   d.Get("protocol").(string),
+		"src":        negateAddress(d.Get("src").(string), d.Get("src_not").(bool)),
+		"dst":        negateAddress(d.Get("dst").(string), d.Get("dst_not").(bool)),
+		"statetype":  d.Get("state_type").(string),
+		"log":        d.Get("log").(bool),
+		"disabled":   d.Get("disabled").(bool),
+		"descr":      d.Get("description").(string),
+	}
+
+	optional := map[string]string{
+		"srcport":   "srcport",
+		"dstport":   "dstport",
+		"gateway":   "gateway",
+		"schedule":  "sched",
+		"direction": "direction",
+	}
+	for key, field := range optional {
+		if value, ok := d.GetOk(key); ok {
+			request[field] = value
+		}
+	}
+
+	if d.Get("floating").(bool) {
+		request["floating"] = true
+	}
+
+	return request
+}
+
+// firewallRuleRequestFromRule builds the request that writes rule back
+// unchanged.
+func firewallRuleRequestFromRule(rule *api.FirewallRule) api.Request {
+	request := api.Request{
+		"tracker":    string(rule.Tracker),
+		"interface":  rule.Interface,
+		"type":       rule.Type,
+		"ipprotocol": rule.IpProtocol,
+		"protocol":   firewallRuleProtocol(rule.Protocol),
+		"src":        negateAddress(rule.Source.AddressString(), rule.Source.Inverted()),
+		"dst":        negateAddress(rule.Destination.AddressString(), rule.Destination.Inverted()),
+		"statetype":  rule.StateType,
+		"log":        bool(rule.Log),
+		"disabled":   bool(rule.Disabled),
+		"descr":      rule.Description,
+	}
+
+	optional := map[string]string{
+		"srcport":   rule.Source.PortValue(),
+		"dstport":   rule.Destination.PortValue(),
+		"gateway":   rule.Gateway,
+		"sched":     rule.Schedule,
+		"direction": rule.Direction,
+	}
+	for field, value := range optional {
+		if len(value) > 0 {
+			request[field] = value
+		}
+	}
+
+	if rule.Floating {
+		request["floating"] = true
+	}
+
+	return request
+}
+
+// negateAddress prefixes an address with "!", which is how the API takes an
+// inverted source or destination.
+func negateAddress(address string, not bool) string {
+	if not {
+		return "!" + address
+	}
+	return address
+}
+
+// pfSense leaves the protocol out of the rule when it matches any protocol.
+func firewallRuleProtocol(protocol string) string {
+	if len(protocol) <= 0 {
+		return "any"
+	}
+	return protocol
+}
+
+func findFirewallRule(rules []*api.FirewallRule, tracker string) *api.FirewallRule {
+	for _, rule := range rules {
+		if string(rule.Tracker) == tracker {
+			return rule
+		}
+	}
+	return nil
+}
+
+func firewallRuleResourceId(tracker string) string {
+	return tracker
+}
+
+var firewallRuleRsId = regexp.MustCompile("^([0-9]+)$")
+
+func parseFirewallRuleResourceId(resId string) (tracker string, err error) {
+	if !firewallRuleRsId.MatchString(resId) {
+		return "", fmt.Errorf("invalid resource format: %s. must be a firewall rule tracker", resId)
+	}
+	tracker = resId
+	return
+}
@@ -0,0 +1,324 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+)
+
+func resourceNatOneToOne() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNatOneToOneCreate,
+		Read:   resourceNatOneToOneRead,
+		Update: resourceNatOneToOneUpdate,
+		Delete: resourceNatOneToOneDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceNatOneToOneImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"external": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.IsIPAddress,
+			},
+			"internal": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.Any(validation.IsIPAddress, validation.IsCIDR),
+			},
+			"dst": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "any",
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"dst_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"ip_protocol": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "inet",
+				ValidateFunc: validation.StringInSlice([]string{"inet", "inet6"}, false),
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"disabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"nat_reflection": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "system",
+				ValidateFunc: validation.StringInSlice([]string{"system", "enable", "disable"}, false),
+			},
+			"nobinat": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+	}
+}
+
+func resourceNatOneToOneCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := newNatTracker()
+	if err != nil {
+		return err
+	}
+
+	request := natOneToOneRequest(d, tracker)
+	request["apply"] = pconf.applyNow()
+
+	err = pconf.write(sectionNatOneToOne, func() error {
+		err := client.CreateNatOneToOneMapping(request)
+		if err != nil {
+			return err
+		}
+
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			data, err1 := client.ListNatOneToOneMappings()
+			if err1 != nil {
+				return false, err1
+			}
+			id, _ := findNatOneToOne(data, tracker)
+			return id >= 0, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find created 1:1 NAT mapping! tracker: %s, error: %s", tracker, err)
+		}
+
+		d.SetId(natResourceId(tracker))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceNatOneToOneRead(d, meta)
+}
+
+func resourceNatOneToOneRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	data, err := pconf.natOneToOneMappings()
+	if err != nil {
+		return err
+	}
+
+	id, mapping := findNatOneToOne(data, tracker)
+	if id < 0 {
+		log.Printf("[WARN] 1:1 NAT mapping %s not found, removing it from state", tracker)
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(natResourceId(tracker))
+
+	values := map[string]interface{}{
+		"interface":      mapping.Interface,
+		"external":       mapping.External,
+		"internal":       mapping.Source.AddressString(),
+		"dst":            mapping.Destination.AddressString(),
+		"dst_not":        mapping.Destination.Inverted(),
+		"ip_protocol":    natIpProtocol(mapping.IpProtocol),
+		"description":    natDescription(mapping.Description),
+		"disabled":       bool(mapping.Disabled),
+		"nat_reflection": natReflection(mapping.NatReflection),
+		"nobinat":        bool(mapping.NoBinat),
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceNatOneToOneUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	err = pconf.write(sectionNatOneToOne, func() error {
+		data, err := client.ListNatOneToOneMappings()
+		if err != nil {
+			return err
+		}
+
+		id, _ := findNatOneToOne(data, tracker)
+		if id < 0 {
+			return fmt.Errorf("1:1 NAT mapping for this tracker do not exists! tracker: %s", tracker)
+		}
+
+		request := natOneToOneRequest(d, tracker)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		return client.UpdateNatOneToOneMapping(request)
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceNatOneToOneRead(d, meta)
+}
+
+func resourceNatOneToOneDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return pconf.write(sectionNatOneToOne, func() error {
+		data, err := client.ListNatOneToOneMappings()
+		if err != nil {
+			return err
+		}
+
+		id, _ := findNatOneToOne(data, tracker)
+		if id < 0 {
+			log.Printf("[WARN] 1:1 NAT mapping %s already deleted", tracker)
+			return nil
+		}
+
+		err = client.DeleteNatOneToOneMapping(id, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Like outbound mappings, untracked 1:1 mappings are imported by their index
+// or interface/index.
+func resourceNatOneToOneImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if !isNatIndexResourceId(d.Id()) {
+		return []*schema.ResourceData{d}, nil
+	}
+
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	var tracker string
+	err := pconf.write(sectionNatOneToOne, func() error {
+		data, err := client.ListNatOneToOneMappings()
+		if err != nil {
+			return err
+		}
+
+		id, err := parseNatIndexResourceId(d.Id(), len(data), func(i int) string {
+			return data[i].Interface
+		})
+		if err != nil {
+			return err
+		}
+
+		mapping := data[id]
+		tracker = natTracker(mapping.Description)
+		if len(tracker) > 0 {
+			return nil
+		}
+
+		tracker, err = newNatTracker()
+		if err != nil {
+			return err
+		}
+
+		request := natOneToOneRequestFromMapping(mapping)
+		request["descr"] = natTrackerDescr(mapping.Description, tracker)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		log.Printf("[INFO] adding tracker %s to 1:1 NAT mapping %s", tracker, d.Id())
+		return client.UpdateNatOneToOneMapping(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.SetId(natResourceId(tracker))
+	return []*schema.ResourceData{d}, nil
+}
+
+func natOneToOneRequest(d *schema.ResourceData, tracker string) api.Request {
+	request := api.Request{
+		"interface":  d.Get("interface").(string),
+		"ipprotocol": d.Get("ip_protocol").(string),
+		"external":   d.Get("external").(string),
+		"src":        d.Get("internal").(string),
+		"dst":        negateAddress(d.Get("dst").(string), d.Get("dst_not").(bool)),
+		"descr":      natTrackerDescr(d.Get("description").(string), tracker),
+		"disabled":   d.Get("disabled").(bool),
+		"nobinat":    d.Get("nobinat").(bool),
+	}
+
+	request["natreflection"] = ""
+	if reflection := d.Get("nat_reflection").(string); reflection != "system" {
+		request["natreflection"] = reflection
+	}
+
+	return request
+}
+
+// natOneToOneRequestFromMapping builds the request that writes mapping back
+// unchanged.
+func natOneToOneRequestFromMapping(mapping *api.NatOneToOne) api.Request {
+	return api.Request{
+		"interface":     mapping.Interface,
+		"ipprotocol":    natIpProtocol(mapping.IpProtocol),
+		"external":      mapping.External,
+		"src":           mapping.Source.AddressString(),
+		"dst":           negateAddress(mapping.Destination.AddressString(), mapping.Destination.Inverted()),
+		"descr":         mapping.Description,
+		"disabled":      bool(mapping.Disabled),
+		"nobinat":       bool(mapping.NoBinat),
+		"natreflection": mapping.NatReflection,
+	}
+}
+
+func findNatOneToOne(data []*api.NatOneToOne, tracker string) (int, *api.NatOneToOne) {
+	if len(tracker) <= 0 {
+		return -1, nil
+	}
+	for key, mapping := range data {
+		if natTracker(mapping.Description) == tracker {
+			return key, mapping
+		}
+	}
+	return -1, nil
+}
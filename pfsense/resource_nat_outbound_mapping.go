@@ -0,0 +1,392 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"regexp"
+	"strconv"
+)
+
+func resourceNatOutboundMapping() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNatOutboundMappingCreate,
+		Read:   resourceNatOutboundMappingRead,
+		Update: resourceNatOutboundMappingUpdate,
+		Delete: resourceNatOutboundMappingDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceNatOutboundMappingImport,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"protocol": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "any",
+				ValidateFunc: validation.StringInSlice([]string{"any", "tcp", "udp", "tcp/udp", "icmp", "esp", "ah", "gre", "ipv6", "igmp", "pim", "ospf"}, false),
+			},
+			"src": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"srcport": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"dst": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "any",
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"dst_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"dstport": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"target": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"static_port": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"pool_options": {
+				Type:     schema.TypeString,
+				Optional: true,
+				ValidateFunc: validation.StringInSlice([]string{
+					"round-robin", "round-robin sticky-address", "random", "random sticky-address", "source-hash", "bitmask",
+				}, false),
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"disabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"nosync": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+		},
+	}
+}
+
+func resourceNatOutboundMappingCreate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := newNatTracker()
+	if err != nil {
+		return err
+	}
+
+	request := natOutboundMappingRequest(d, tracker)
+	request["apply"] = pconf.applyNow()
+
+	err = pconf.write(sectionNatOutbound, func() error {
+		err := client.CreateNatOutboundMapping(request)
+		if err != nil {
+			return err
+		}
+
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			data, err1 := client.ListNatOutboundMappings()
+			if err1 != nil {
+				return false, err1
+			}
+			id, _ := findNatOutboundMapping(data, tracker)
+			return id >= 0, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find created outbound NAT mapping! tracker: %s, error: %s", tracker, err)
+		}
+
+		d.SetId(natResourceId(tracker))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceNatOutboundMappingRead(d, meta)
+}
+
+func resourceNatOutboundMappingRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	data, err := pconf.natOutboundMappings()
+	if err != nil {
+		return err
+	}
+
+	id, mapping := findNatOutboundMapping(data, tracker)
+	if id < 0 {
+		log.Printf("[WARN] outbound NAT mapping %s not found, removing it from state", tracker)
+		d.SetId("")
+		return nil
+	}
+
+	d.SetId(natResourceId(tracker))
+
+	values := map[string]interface{}{
+		"interface":    mapping.Interface,
+		"protocol":     firewallRuleProtocol(mapping.Protocol),
+		"src":          mapping.Source.AddressString(),
+		"srcport":      mapping.SourcePort,
+		"dst":          mapping.Destination.AddressString(),
+		"dst_not":      mapping.Destination.Inverted(),
+		"dstport":      mapping.DestinationPort,
+		"target":       natOutboundTarget(mapping),
+		"static_port":  bool(mapping.StaticNatPort),
+		"pool_options": mapping.PoolOptions,
+		"description":  natDescription(mapping.Description),
+		"disabled":     bool(mapping.Disabled),
+		"nosync":       bool(mapping.NoSync),
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceNatOutboundMappingUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	err = pconf.write(sectionNatOutbound, func() error {
+		data, err := client.ListNatOutboundMappings()
+		if err != nil {
+			return err
+		}
+
+		id, _ := findNatOutboundMapping(data, tracker)
+		if id < 0 {
+			return fmt.Errorf("outbound NAT mapping for this tracker do not exists! tracker: %s", tracker)
+		}
+
+		request := natOutboundMappingRequest(d, tracker)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		return client.UpdateNatOutboundMapping(request)
+	})
+	if err != nil {
+		return err
+	}
+
+	return resourceNatOutboundMappingRead(d, meta)
+}
+
+func resourceNatOutboundMappingDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	tracker, err := parseNatResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	return pconf.write(sectionNatOutbound, func() error {
+		data, err := client.ListNatOutboundMappings()
+		if err != nil {
+			return err
+		}
+
+		id, _ := findNatOutboundMapping(data, tracker)
+		if id < 0 {
+			log.Printf("[WARN] outbound NAT mapping %s already deleted", tracker)
+			return nil
+		}
+
+		err = client.DeleteNatOutboundMapping(id, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Mappings made in the GUI have no tracker yet, they are imported by their
+// index or interface/index and get one written into their description.
+func resourceNatOutboundMappingImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if !isNatIndexResourceId(d.Id()) {
+		return []*schema.ResourceData{d}, nil
+	}
+
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	var tracker string
+	err := pconf.write(sectionNatOutbound, func() error {
+		data, err := client.ListNatOutboundMappings()
+		if err != nil {
+			return err
+		}
+
+		id, err := parseNatIndexResourceId(d.Id(), len(data), func(i int) string {
+			return data[i].Interface
+		})
+		if err != nil {
+			return err
+		}
+
+		mapping := data[id]
+		tracker = natTracker(mapping.Description)
+		if len(tracker) > 0 {
+			return nil
+		}
+
+		tracker, err = newNatTracker()
+		if err != nil {
+			return err
+		}
+
+		request := natOutboundMappingRequestFromMapping(mapping)
+		request["descr"] = natTrackerDescr(mapping.Description, tracker)
+		request["id"] = id
+		request["apply"] = pconf.applyNow()
+
+		log.Printf("[INFO] adding tracker %s to outbound NAT mapping %s", tracker, d.Id())
+		return client.UpdateNatOutboundMapping(request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.SetId(natResourceId(tracker))
+	return []*schema.ResourceData{d}, nil
+}
+
+var natIndexRsId = regexp.MustCompile("^(([^/]+)/)?([0-9]+)$")
+
+// A tracker may consist of digits only, it is never taken for an index.
+func isNatIndexResourceId(resId string) bool {
+	return natIndexRsId.MatchString(resId) && !natRsId.MatchString(resId)
+}
+
+// parseNatIndexResourceId returns the index of an index or interface/index
+// import id into a list of count entries, iface giving the interface of each.
+func parseNatIndexResourceId(resId string, count int, iface func(i int) string) (int, error) {
+	idMatch := natIndexRsId.FindStringSubmatch(resId)
+	if idMatch == nil {
+		return -1, fmt.Errorf("invalid resource format: %s. must be index or interface/index", resId)
+	}
+	id, err := strconv.Atoi(idMatch[3])
+	if err != nil {
+		return -1, err
+	}
+	if id >= count || (len(idMatch[2]) > 0 && iface(id) != idMatch[2]) {
+		return -1, fmt.Errorf("NAT mapping for this id do not exists! id: %s", resId)
+	}
+	return id, nil
+}
+
+func natOutboundMappingRequest(d *schema.ResourceData, tracker string) api.Request {
+	request := api.Request{
+		"interface":     d.Get("interface").(string),
+		"protocol":      d.Get("protocol").(string),
+		"src":           d.Get("src").(string),
+		"dst":           negateAddress(d.Get("dst").(string), d.Get("dst_not").(bool)),
+		"target":        d.Get("target").(string),
+		"staticnatport": d.Get("static_port").(bool),
+		"poolopts":      d.Get("pool_options").(string),
+		"descr":         natTrackerDescr(d.Get("description").(string), tracker),
+		"disabled":      d.Get("disabled").(bool),
+		"nosync":        d.Get("nosync").(bool),
+	}
+
+	if srcport, ok := d.GetOk("srcport"); ok {
+		request["srcport"] = srcport
+	}
+	if dstport, ok := d.GetOk("dstport"); ok {
+		request["dstport"] = dstport
+	}
+
+	return request
+}
+
+// natOutboundMappingRequestFromMapping builds the request that writes mapping
+// back unchanged.
+func natOutboundMappingRequestFromMapping(mapping *api.NatOutboundMapping) api.Request {
+	request := api.Request{
+		"interface":     mapping.Interface,
+		"protocol":      firewallRuleProtocol(mapping.Protocol),
+		"src":           mapping.Source.AddressString(),
+		"dst":           negateAddress(mapping.Destination.AddressString(), mapping.Destination.Inverted()),
+		"target":        natOutboundTarget(mapping),
+		"staticnatport": bool(mapping.StaticNatPort),
+		"poolopts":      mapping.PoolOptions,
+		"descr":         mapping.Description,
+		"disabled":      bool(mapping.Disabled),
+		"nosync":        bool(mapping.NoSync),
+	}
+
+	if len(mapping.SourcePort) > 0 {
+		request["srcport"] = mapping.SourcePort
+	}
+	if len(mapping.DestinationPort) > 0 {
+		request["dstport"] = mapping.DestinationPort
+	}
+
+	return request
+}
+
+// natOutboundTarget returns the translation address, which pfSense keeps
+// apart from the target when it is a custom subnet.
+func natOutboundTarget(mapping *api.NatOutboundMapping) string {
+	if mapping.Target == "other-subnet" {
+		return fmt.Sprintf("%s/%s", mapping.TargetIp, mapping.TargetSubnet)
+	}
+	return mapping.Target
+}
+
+func findNatOutboundMapping(data []*api.NatOutboundMapping, tracker string) (int, *api.NatOutboundMapping) {
+	if len(tracker) <= 0 {
+		return -1, nil
+	}
+	for key, mapping := range data {
+		if natTracker(mapping.Description) == tracker {
+			return key, mapping
+		}
+	}
+	return -1, nil
+}
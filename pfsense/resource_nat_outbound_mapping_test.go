@@ -0,0 +1,38 @@
+package pfsense
+
+import (
+	"testing"
+)
+
+func TestParseNatIndexResourceId(t *testing.T) {
+	interfaces := []string{"wan", "wan", "opt1"}
+	iface := func(i int) string { return interfaces[i] }
+
+	cases := []struct {
+		resId string
+		id    int
+		ok    bool
+	}{
+		{"0", 0, true},
+		{"2", 2, true},
+		{"wan/1", 1, true},
+		{"opt1/2", 2, true},
+		{"lan/0", -1, false},
+		{"3", -1, false},
+		{"wan/3", -1, false},
+		{"wan/", -1, false},
+		{"-1", -1, false},
+		{"0123456789abcdef", -1, false},
+	}
+
+	for _, c := range cases {
+		id, err := parseNatIndexResourceId(c.resId, len(interfaces), iface)
+		if c.ok != (err == nil) {
+			t.Errorf("parseNatIndexResourceId(%q) error = %v, want ok %v", c.resId, err, c.ok)
+			continue
+		}
+		if id != c.id {
+			t.Errorf("parseNatIndexResourceId(%q) = %d, want %d", c.resId, id, c.id)
+		}
+	}
+}
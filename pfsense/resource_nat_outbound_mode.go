@@ -0,0 +1,98 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+)
+
+const natOutboundModeId = "nat_outbound_mode"
+
+// The API calls manual outbound NAT "advanced", the name used by the GUI is
+// exposed instead.
+var natOutboundModes = map[string]string{
+	"automatic": "automatic",
+	"hybrid":    "hybrid",
+	"manual":    "advanced",
+	"disabled":  "disabled",
+}
+
+func resourceNatOutboundMode() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNatOutboundModeCreate,
+		Read:   resourceNatOutboundModeRead,
+		Update: resourceNatOutboundModeUpdate,
+		Delete: resourceNatOutboundModeDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"mode": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"automatic", "hybrid", "manual", "disabled"}, false),
+			},
+		},
+	}
+}
+
+func resourceNatOutboundModeCreate(d *schema.ResourceData, meta interface{}) error {
+	err := resourceNatOutboundModeUpdate(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(natOutboundModeId)
+	return resourceNatOutboundModeRead(d, meta)
+}
+
+func resourceNatOutboundModeRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	if d.Id() != natOutboundModeId {
+		return fmt.Errorf("invalid resource format: %s. must be %s", d.Id(), natOutboundModeId)
+	}
+
+	var mode string
+	err := pconf.Locks.Read(sectionNatOutbound, func() error {
+		data, err := client.GetNatOutboundMode()
+		if err != nil {
+			return err
+		}
+		mode = data.Mode
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, apiMode := range natOutboundModes {
+		if apiMode == mode {
+			return d.Set("mode", name)
+		}
+	}
+	return fmt.Errorf("unsupported outbound NAT mode: %s", mode)
+}
+
+func resourceNatOutboundModeUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	mode := natOutboundModes[d.Get("mode").(string)]
+
+	return pconf.write(sectionNatOutbound, func() error {
+		return client.UpdateNatOutboundMode(mode, pconf.applyNow())
+	})
+}
+
+// Deleting the resource puts pfSense back to its default automatic mode.
+func resourceNatOutboundModeDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	return pconf.write(sectionNatOutbound, func() error {
+		return client.UpdateNatOutboundMode(natOutboundModes["automatic"], pconf.applyDeleteNow())
+	})
+}
@@ -1,14 +1,15 @@
 package pfsense
 
 import (
-	"crypto/sha256"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"github.com/go-resty/resty/v2"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
 	"regexp"
-	"strconv"
-	"time"
+	"strings"
 )
 
 func resourceNatPortForward() *schema.Resource {
@@ -18,7 +19,17 @@ func resourceNatPortForward() *schema.Resource {
 		Update: resourceNatPortForwardUpdate,
 		Delete: resourceNatPortForwardDelete,
 		Importer: &schema.ResourceImporter{
-			State: schema.ImportStatePassthrough,
+			State: resourceNatPortForwardImport,
+		},
+		CustomizeDiff: resourceNatPortForwardCustomizeDiff,
+
+		SchemaVersion: 1,
+		StateUpgraders: []schema.StateUpgrader{
+			{
+				Version: 0,
+				Type:    resourceNatPortForwardV0().CoreConfigSchema().ImpliedType(),
+				Upgrade: resourceNatPortForwardStateUpgradeV0,
+			},
 		},
 
 		Schema: map[string]*schema.Schema{
@@ -62,331 +73,460 @@ func resourceNatPortForward() *schema.Resource {
 				Optional:     true,
 				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
 			},
+			"src_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"dst_not": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"ip_protocol": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "inet",
+				ValidateFunc: validation.StringInSlice([]string{"inet", "inet6"}, false),
+			},
+			"description": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"disabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"nat_reflection": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "system",
+				ValidateFunc: validation.StringInSlice([]string{"system", "enable", "purenat", "disable"}, false),
+			},
+			"filter_rule_association": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "none",
+				ValidateFunc: validation.StringInSlice([]string{"none", "pass", "associated"}, false),
+			},
+			"nordr": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"nosync": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"tracker": {
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 		},
 	}
 }
 
 func resourceNatPortForwardCreate(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-	lock := pconf.Mutex
 	client := pconf.Client
 
-	request := map[string]interface{}{
-		"interface":  d.Get("interface").(string),
-		"protocol":   d.Get("protocol").(string),
-		"src":        d.Get("src").(string),
-		"dst":        d.Get("dst").(string),
-		"srcport":    d.Get("srcport").(string),
-		"dstport":    d.Get("dstport").(string),
-		"target":     d.Get("target").(string),
-		"local-port": d.Get("local_port").(string),
-		"top":		  true,
-		"apply":	  true,
-	}
-
-	lock.Lock()
-	resp, err := client.R().
-		SetBody(request).
-		SetResult(&CreateNatPortForwardResponse{}).
-		ForceContentType("application/json").
-		Post(PFSenseApiUri.NATPortForward)
-
+	tracker, err := newNatTracker()
 	if err != nil {
-		lock.Unlock()
 		return err
 	}
 
-	if resp.StatusCode() != 200 {
-		lock.Unlock()
-		return fmt.Errorf("invalid response code on create: %d, data: %s, request: %s", resp.StatusCode(), resp, request)
-	}
+	request := natPortForwardRequest(d, tracker, nil)
+	request["top"] = true
+	request["apply"] = pconf.applyNow()
 
-	time.Sleep(100 * time.Millisecond)
-
-	result := resp.Result().(*CreateNatPortForwardResponse)
-
-	data, err1 := fetchNATList(client)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("nat list is empty! data: %s", data)
-	}
+	err = pconf.write(sectionNat, func() error {
+		err := client.CreateNatPortForward(request)
+		if err != nil {
+			return err
+		}
 
-	var id int = -1
-	for key := range data {
-		if data[key].hashCode() == result.Data.hashCode() {
-			id = key
+		err = waitForObject(pconf.Timeout, func() (bool, error) {
+			data, err1 := client.ListNatPortForwards()
+			if err1 != nil {
+				return false, err1
+			}
+			id, _ := findNatRule(data, tracker)
+			return id >= 0, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find created NAT rule! tracker: %s, error: %s", tracker, err)
 		}
-	}
 
-	if id < 0 {
-		lock.Unlock()
-		return fmt.Errorf("failed to find created NAT rule")
+		d.SetId(natResourceId(tracker))
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	d.SetId(natResourceId(request["interface"].(string), id))
-
-	lock.Unlock()
 	return resourceNatPortForwardRead(d, meta)
 }
 
 func resourceNatPortForwardRead(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
-	client := pconf.Client
-	lock := pconf.Mutex
-	iface, id, err := parseNatResourceId(d.Id())
+
+	data, err := pconf.natPortForwards()
 	if err != nil {
-		d.SetId("")
 		return err
 	}
 
-	lock.Lock()
-	data, err1 := fetchNATList(client)
-	if err1 != nil {
-		lock.Unlock()
-		return err1
-	}
-	if data == nil {
-		lock.Unlock()
-		return fmt.Errorf("nat list is empty! data: %s", data)
-	}
-
-	nat := data[id]
-
-	d.SetId(natResourceId(iface, id))
-
-	err2 := d.Set("interface", iface)
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("protocol", nat.Protocol)
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("local_port", nat.LocalPort)
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("target", nat.Target)
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("dst", nat.Destination.toAddressString())
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("src", nat.Source.toAddressString())
-	if err2 != nil {
-		lock.Unlock()
-		return err2
-	}
-	err2 = d.Set("srcport", nat.Source.getPort())
-	if err2 != nil {
-		lock.Unlock()
-		return err2
+	id, nat, err := findNatRuleById(data, d.Id(), d.Get)
+	if err != nil {
+		return err
 	}
-	err2 = d.Set("dstport", nat.Destination.getPort())
-	if err2 != nil {
-		lock.Unlock()
-		return err2
+	if id < 0 {
+		log.Printf("[WARN] NAT rule %s not found, removing it from state", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	tracker := natTracker(nat.Description)
+	if len(tracker) > 0 {
+		d.SetId(natResourceId(tracker))
+	}
+
+	values := map[string]interface{}{
+		"interface":               nat.Interface,
+		"protocol":                nat.Protocol,
+		"local_port":              nat.LocalPort,
+		"target":                  nat.Target,
+		"dst":                     nat.Destination.AddressString(),
+		"dst_not":                 nat.Destination.Inverted(),
+		"src":                     nat.Source.AddressString(),
+		"src_not":                 nat.Source.Inverted(),
+		"srcport":                 nat.Source.PortString(),
+		"dstport":                 nat.Destination.PortString(),
+		"ip_protocol":             natIpProtocol(nat.IpProtocol),
+		"description":             natDescription(nat.Description),
+		"disabled":                bool(nat.Disabled),
+		"nat_reflection":          natReflection(nat.NatReflection),
+		"filter_rule_association": natFilterRuleAssociation(nat.AssociatedRuleId),
+		"nordr":                   bool(nat.NoRdr),
+		"nosync":                  bool(nat.NoSync),
+		"tracker":                 tracker,
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
 	}
 
-	lock.Unlock()
 	return nil
 }
 
 func resourceNatPortForwardDelete(d *schema.ResourceData, meta interface{}) error {
 	pconf := meta.(*providerConfiguration)
 	client := pconf.Client
-	lock := pconf.Mutex
 
-	_, id, err := parseNatResourceId(d.Id())
-	if err != nil {
-		d.SetId("")
+	return pconf.write(sectionNat, func() error {
+		data, err := client.ListNatPortForwards()
+		if err != nil {
+			return err
+		}
+
+		id, _, err := findNatRuleById(data, d.Id(), d.Get)
+		if err != nil {
+			return err
+		}
+		if id < 0 {
+			log.Printf("[WARN] NAT rule %s already deleted", d.Id())
+			return nil
+		}
+
+		err = client.DeleteNatPortForward(id, pconf.applyDeleteNow())
+		if api.IsNotFound(err) {
+			return nil
+		}
 		return err
-	}
+	})
+}
+
+func resourceNatPortForwardUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
 
-	var request = map[string]interface{}{
-		"id": id,
-		"apply": true,
+	// The rule at a legacy id is checked against the state before the update,
+	// and gets its tracker with it.
+	previous := func(key string) interface{} {
+		value, _ := d.GetChange(key)
+		return value
 	}
-	lock.Lock()
-	resp, err := client.R().
-		SetBody(request).
-		Delete(PFSenseApiUri.NATPortForward)
 
-	lock.Unlock()
+	err := pconf.write(sectionNat, func() error {
+		data, err := client.ListNatPortForwards()
+		if err != nil {
+			return err
+		}
+
+		id, nat, err := findNatRuleById(data, d.Id(), previous)
+		if err != nil {
+			return err
+		}
+		if id < 0 {
+			return fmt.Errorf("NAT rule for this id do not exists! id: %s", d.Id())
+		}
+
+		tracker := natTracker(nat.Description)
+		if len(tracker) <= 0 {
+			tracker, err = newNatTracker()
+			if err != nil {
+				return err
+			}
+			log.Printf("[INFO] adding tracker %s to NAT rule %s", tracker, d.Id())
+		}
+
+		err = updateNatPortForward(pconf, id, nat, natPortForwardRequest(d, tracker, nat))
+		if err != nil {
+			return err
+		}
+
+		d.SetId(natResourceId(tracker))
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code on delete: %d", resp.StatusCode())
-	}
-
-	return nil
+	return resourceNatPortForwardRead(d, meta)
 }
 
-func resourceNatPortForwardUpdate(d *schema.ResourceData, meta interface{}) error {
-	pconf := meta.(*providerConfiguration)
+// updateNatPortForward replaces the rule at id with request. Older API
+// releases have no PUT handler for port forwards, once one answered so every
+// update goes through recreateNatPortForward. Callers hold the NAT write lock.
+func updateNatPortForward(pconf *providerConfiguration, id int, original *api.NatPortForward, request api.Request) error {
 	client := pconf.Client
-	lock := pconf.Mutex
+	apply := pconf.applyNow()
+
+	if !pconf.natPutUnsupported {
+		request["id"] = id
+		request["apply"] = apply
+		err := client.UpdateNatPortForward(request)
+		if !natUpdateUnsupported(err) {
+			return err
+		}
+		log.Printf("[WARN] the API does not support updating NAT rules, falling back to delete and create")
+		pconf.natPutUnsupported = true
+		delete(request, "id")
+	}
+
+	return recreateNatPortForward(client, id, original, request, apply)
+}
 
-	_, id, err := parseNatResourceId(d.Id())
+// recreateNatPortForward deletes the rule at id and creates request at the
+// same position, unless request already places itself. The original
+// definition is restored when the new one is rejected.
+func recreateNatPortForward(client *api.Client, id int, original *api.NatPortForward, request api.Request, apply bool) error {
+	err := client.DeleteNatPortForward(id, apply)
 	if err != nil {
-		d.SetId("")
 		return err
 	}
 
-	var request = map[string]interface{}{
-		"id": id,
+	reassociate(request)
+	if _, ok := request["top"]; !ok {
+		placeNatPortForward(request, id)
+	}
+	request["apply"] = apply
+	err = client.CreateNatPortForward(request)
+	if err == nil {
+		return nil
 	}
 
-	lock.Lock()
-	resp, err := client.R().
-		SetBody(request).
-		Delete(PFSenseApiUri.NATPortForward)
-	if err != nil {
-		lock.Unlock()
-		return err
+	rollback := natPortForwardRequestFromRule(original)
+	reassociate(rollback)
+	placeNatPortForward(rollback, id)
+	rollback["apply"] = apply
+	rollbackErr := client.CreateNatPortForward(rollback)
+	if rollbackErr != nil {
+		return fmt.Errorf("%s; rollback to original NAT rule failed: %s", err, rollbackErr)
+	}
+
+	return err
+}
+
+// placeNatPortForward makes request land at index id, right after the rule
+// that preceded the deleted one.
+func placeNatPortForward(request api.Request, id int) {
+	if id <= 0 {
+		request["top"] = true
+		return
 	}
+	request["top"] = false
+	request["after"] = id - 1
+}
 
-	requestCreate := map[string]interface{}{
+// natUpdateUnsupported reports whether err says the PUT method is missing.
+// A 404 is not taken as such, it may just as well mean the rule is gone.
+func natUpdateUnsupported(err error) bool {
+	apiErr, ok := err.(*api.Error)
+	if !ok {
+		return false
+	}
+	return apiErr.StatusCode == 405 || apiErr.StatusCode == 501
+}
+
+// natPortForwardRequest builds the rule from the config. current is the rule
+// being updated, nil on create, so an associated filter rule is kept rather
+// than a new one added on every update.
+func natPortForwardRequest(d *schema.ResourceData, tracker string, current *api.NatPortForward) api.Request {
+	request := api.Request{
 		"interface":  d.Get("interface").(string),
 		"protocol":   d.Get("protocol").(string),
-		"src":        d.Get("src").(string),
-		"dst":        d.Get("dst").(string),
+		"ipprotocol": d.Get("ip_protocol").(string),
+		"src":        negateAddress(d.Get("src").(string), d.Get("src_not").(bool)),
+		"dst":        negateAddress(d.Get("dst").(string), d.Get("dst_not").(bool)),
 		"srcport":    d.Get("srcport").(string),
 		"dstport":    d.Get("dstport").(string),
 		"target":     d.Get("target").(string),
 		"local-port": d.Get("local_port").(string),
-		"top":		  true,
-		"apply":	  true,
+		"descr":      natTrackerDescr(d.Get("description").(string), tracker),
+		"disabled":   d.Get("disabled").(bool),
+		"nordr":      d.Get("nordr").(bool),
+		"nosync":     d.Get("nosync").(bool),
 	}
 
-	resp, err = client.R().
-		SetBody(requestCreate).
-		SetResult(&CreateNatPortForwardResponse{}).
-		ForceContentType("application/json").
-		Post(PFSenseApiUri.NATPortForward)
-	lock.Unlock()
-	if err != nil {
-		return err
+	request["natreflection"] = ""
+	if reflection := d.Get("nat_reflection").(string); reflection != "system" {
+		request["natreflection"] = reflection
 	}
 
-	if resp.StatusCode() != 200 {
-		return fmt.Errorf("invalid response code on create: %d, data: %s, request: %s", resp.StatusCode(), resp, request)
+	switch d.Get("filter_rule_association").(string) {
+	case "pass":
+		request["associated-rule-id"] = "pass"
+	case "associated":
+		request["associated-rule-id"] = "add-associated"
+		if current != nil && natFilterRuleAssociation(current.AssociatedRuleId) == "associated" {
+			request["associated-rule-id"] = current.AssociatedRuleId
+		}
+	default:
+		request["associated-rule-id"] = ""
 	}
 
-	return nil
+	return request
 }
 
-func natResourceId(iface string, id int) string {
-	return fmt.Sprintf("%s/%s", iface, strconv.Itoa(id))
+func natPortForwardRequestFromRule(nat *api.NatPortForward) api.Request {
+	request := api.Request{
+		"interface":     nat.Interface,
+		"protocol":      nat.Protocol,
+		"ipprotocol":    natIpProtocol(nat.IpProtocol),
+		"src":           negateAddress(nat.Source.AddressString(), nat.Source.Inverted()),
+		"dst":           negateAddress(nat.Destination.AddressString(), nat.Destination.Inverted()),
+		"srcport":       nat.Source.PortString(),
+		"dstport":       nat.Destination.PortString(),
+		"target":        nat.Target,
+		"local-port":    nat.LocalPort,
+		"descr":         nat.Description,
+		"disabled":      bool(nat.Disabled),
+		"nordr":         bool(nat.NoRdr),
+		"nosync":        bool(nat.NoSync),
+		"natreflection": nat.NatReflection,
+	}
+
+	if natFilterRuleAssociation(nat.AssociatedRuleId) != "none" {
+		request["associated-rule-id"] = nat.AssociatedRuleId
+	}
+
+	return request
 }
 
-var natRsId = regexp.MustCompile("([^/]+)/(\\d+)")
-
-func parseNatResourceId(resId string) (iface string, id int, err error) {
-	if !natRsId.MatchString(resId) {
-		return "", -1, fmt.Errorf("invalid resource format: %s. must be itnreface/mac", resId)
+// reassociate asks for a new associated filter rule when request links to
+// one, as the old filter rule goes away with the deleted NAT rule.
+func reassociate(request api.Request) {
+	if id, ok := request["associated-rule-id"].(string); ok && natFilterRuleAssociation(id) == "associated" {
+		request["associated-rule-id"] = "add-associated"
 	}
-	idMatch := rxRsId.FindStringSubmatch(resId)
-	iface = idMatch[1]
-	id, err = strconv.Atoi(idMatch[2])
-	return
 }
 
-func fetchNATList(client *resty.Client) ([]*NatPortForward, error) {
-	resp, err := client.R().
-		SetResult(&ReadNatPortForwardArrayResponse{}).
-		ForceContentType("application/json").
-		Get(PFSenseApiUri.NATPortForward)
-
-	if err != nil {
-		return nil, err
+func natIpProtocol(ipProtocol string) string {
+	if len(ipProtocol) <= 0 {
+		return "inet"
 	}
+	return ipProtocol
+}
 
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("invalid response code on fetch: %d", resp.StatusCode())
+func natReflection(reflection string) string {
+	if len(reflection) <= 0 {
+		return "system"
 	}
+	return reflection
+}
 
-	var result = resp.Result().(*ReadNatPortForwardArrayResponse)
-
-	if len(result.Data) <= 0 {
-		return nil, nil
+// The association is stored as "pass" for a pass rule, or as the id of the
+// linked filter rule, which always starts with "nat_".
+func natFilterRuleAssociation(associatedRuleId string) string {
+	switch {
+	case associatedRuleId == "pass":
+		return "pass"
+	case strings.HasPrefix(associatedRuleId, "nat_"):
+		return "associated"
 	}
-	return result.Data, nil
+	return "none"
 }
 
-type ReadNatPortForwardArrayResponse struct {
-	ApiBaseResponse
-	Data []*NatPortForward `json:"data"`
+func natResourceId(tracker string) string {
+	return tracker
 }
 
-type ReadNatPortForwardMapResponse struct {
-	ApiBaseResponse
-	Data map[string]*NatPortForward `json:"data"`
-}
+var natRsId = regexp.MustCompile("^([0-9a-f]{16})$")
 
-type CreateNatPortForwardResponse struct {
-	ApiBaseResponse
-	Data *NatPortForward `json:"data"`
+func parseNatResourceId(resId string) (tracker string, err error) {
+	if !natRsId.MatchString(resId) {
+		return "", fmt.Errorf("invalid resource format: %s. must be a NAT rule tracker (%s), or interface/index on import", resId, natRsId.String())
+	}
+	tracker = resId
+	return
 }
 
-type NatPortForward struct {
-	Interface   string                  `json:"interface"`
-	Protocol    string                  `json:"protocol"`
-	Source      *NatSourceOrDestination `json:"source"`
-	Destination *NatSourceOrDestination `json:"destination"`
-	Target      string                  `json:"target"`
-	LocalPort   string                  `json:"local-port"`
-}
+// NAT port forwards have no identifier of their own besides their position in
+// the rule list, so a tracker is kept in the rule description instead.
+var natTrackerRegex = regexp.MustCompile("\\[tf:([0-9a-f]{16})\\]")
 
-type NatSourceOrDestination struct {
-	Address string `json:"address"`
-	Any     string `json:"any"`
-	Network string `json:"network"`
-	Not     string `json:"not"`
-	Port    string `json:"port"`
+func newNatTracker() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
 }
 
-func (d NatSourceOrDestination) toAddressString() string {
-	var result = ""
-	if len(d.Address) > 0 {
-		result += d.Address
-	} else if len(d.Network) > 0 {
-		result += d.Network
-	} else {
-		result += "any"
+func natTrackerDescr(description string, tracker string) string {
+	if len(description) <= 0 {
+		return fmt.Sprintf("[tf:%s]", tracker)
 	}
-	return result
+	return fmt.Sprintf("%s [tf:%s]", description, tracker)
 }
 
-func (d NatSourceOrDestination) hashCode() string {
-	return fmt.Sprintf("%x\n", sha256.Sum256([]byte(
-		d.Port+d.Any+d.Network+d.Address+d.Not,
-	)))
+// natDescription returns the description without the tracker.
+func natDescription(descr string) string {
+	return strings.TrimSpace(natTrackerRegex.ReplaceAllString(descr, ""))
 }
 
-func (d NatSourceOrDestination) getPort() string {
-	var result = "any"
-	if len(d.Port) > 0 {
-		result = d.Port
+// natTracker returns the tracker kept in descr, empty for unmanaged rules.
+func natTracker(descr string) string {
+	match := natTrackerRegex.FindStringSubmatch(descr)
+	if match == nil {
+		return ""
 	}
-	return result
+	return match[1]
 }
 
-func (d NatPortForward) hashCode() string {
-	return fmt.Sprintf("%x\n", sha256.Sum256([]byte(
-		d.Target+d.LocalPort+d.Protocol+d.Interface+d.Source.hashCode()+d.Destination.hashCode(),
-	)))
+func findNatRule(data []*api.NatPortForward, tracker string) (int, *api.NatPortForward) {
+	// Unmanaged rules have no tracker, an empty one matches none of them.
+	if len(tracker) <= 0 {
+		return -1, nil
+	}
+	for key, nat := range data {
+		if natTracker(nat.Description) == tracker {
+			return key, nat
+		}
+	}
+	return -1, nil
 }
@@ -0,0 +1,156 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Port forwards created before the tracker was introduced are identified by
+// interface/index. Refresh keeps such an id and only reads the rule at that
+// index, once it checked the rule still is the one in the state. The next
+// apply writes a tracker into the rule and switches the id to it, import
+// does so right away.
+var natLegacyRsId = regexp.MustCompile("^([^/]+)/([0-9]+)$")
+
+func resourceNatPortForwardV0() *schema.Resource {
+	return &schema.Resource{
+		Schema: map[string]*schema.Schema{
+			"interface":  {Type: schema.TypeString, Required: true},
+			"protocol":   {Type: schema.TypeString, Required: true},
+			"src":        {Type: schema.TypeString, Required: true},
+			"dst":        {Type: schema.TypeString, Required: true},
+			"srcport":    {Type: schema.TypeString, Required: true},
+			"dstport":    {Type: schema.TypeString, Required: true},
+			"target":     {Type: schema.TypeString, Optional: true},
+			"local_port": {Type: schema.TypeString, Optional: true},
+		},
+	}
+}
+
+// The state keeps its legacy id, refresh must not write to pfSense.
+func resourceNatPortForwardStateUpgradeV0(rawState map[string]interface{}, meta interface{}) (map[string]interface{}, error) {
+	return rawState, nil
+}
+
+// A legacy id shows up in the plan as a tracker to be assigned.
+func resourceNatPortForwardCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if natLegacyRsId.MatchString(d.Id()) {
+		return d.SetNewComputed("tracker")
+	}
+	return nil
+}
+
+func resourceNatPortForwardImport(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	if natLegacyRsId.MatchString(d.Id()) {
+		tracker, err := adoptLegacyNatRule(meta.(*providerConfiguration), d.Id())
+		if err != nil {
+			return nil, err
+		}
+		d.SetId(natResourceId(tracker))
+	}
+	return []*schema.ResourceData{d}, nil
+}
+
+// adoptLegacyNatRule returns the tracker of the rule at the legacy id,
+// writing a new one into its description when it has none yet. It trusts the
+// id, which only holds on import where the user names the rule.
+func adoptLegacyNatRule(pconf *providerConfiguration, legacyId string) (string, error) {
+	client := pconf.Client
+
+	var tracker string
+	err := pconf.write(sectionNat, func() error {
+		data, err := client.ListNatPortForwards()
+		if err != nil {
+			return err
+		}
+
+		id, nat, err := findLegacyNatRule(data, legacyId)
+		if err != nil {
+			return err
+		}
+
+		tracker = natTracker(nat.Description)
+		if len(tracker) > 0 {
+			return nil
+		}
+
+		tracker, err = newNatTracker()
+		if err != nil {
+			return err
+		}
+
+		request := natPortForwardRequestFromRule(nat)
+		request["descr"] = natTrackerDescr(nat.Description, tracker)
+
+		log.Printf("[INFO] adding tracker %s to NAT rule %s", tracker, legacyId)
+		return updateNatPortForward(pconf, id, nat, request)
+	})
+	return tracker, err
+}
+
+// findNatRuleById returns the rule of the resource id, -1 when a tracked
+// rule is gone. The rule at a legacy id must match state, which returns the
+// attributes pfSense had when the id was taken.
+func findNatRuleById(data []*api.NatPortForward, resId string, state func(key string) interface{}) (int, *api.NatPortForward, error) {
+	if !natLegacyRsId.MatchString(resId) {
+		tracker, err := parseNatResourceId(resId)
+		if err != nil {
+			return -1, nil, err
+		}
+		id, nat := findNatRule(data, tracker)
+		return id, nat, nil
+	}
+
+	id, nat, err := findLegacyNatRule(data, resId)
+	if err != nil {
+		return -1, nil, err
+	}
+	if mismatch := legacyNatMismatch(nat, state); len(mismatch) > 0 {
+		return -1, nil, fmt.Errorf("NAT rule at %s is not the one in the state, the rules have moved! differences: %s. import the rule by its current interface/index", resId, strings.Join(mismatch, ", "))
+	}
+	return id, nat, nil
+}
+
+func findLegacyNatRule(data []*api.NatPortForward, legacyId string) (int, *api.NatPortForward, error) {
+	idMatch := natLegacyRsId.FindStringSubmatch(legacyId)
+	if idMatch == nil {
+		return -1, nil, fmt.Errorf("invalid resource format: %s. must be interface/index", legacyId)
+	}
+	id, err := strconv.Atoi(idMatch[2])
+	if err != nil {
+		return -1, nil, err
+	}
+	if id >= len(data) || data[id].Interface != idMatch[1] {
+		return -1, nil, fmt.Errorf("NAT rule for this id do not exists! id: %s", legacyId)
+	}
+	return id, data[id], nil
+}
+
+// legacyNatMismatch lists the attributes of the legacy state that differ
+// from nat, in the form the provider used to store them.
+func legacyNatMismatch(nat *api.NatPortForward, state func(key string) interface{}) []string {
+	current := map[string]string{
+		"interface":  nat.Interface,
+		"protocol":   nat.Protocol,
+		"src":        nat.Source.AddressString(),
+		"dst":        nat.Destination.AddressString(),
+		"srcport":    nat.Source.PortString(),
+		"dstport":    nat.Destination.PortString(),
+		"target":     nat.Target,
+		"local_port": nat.LocalPort,
+	}
+
+	var mismatch []string
+	for _, key := range []string{"interface", "protocol", "src", "dst", "srcport", "dstport", "target", "local_port"} {
+		value, _ := state(key).(string)
+		if value != current[key] {
+			mismatch = append(mismatch, fmt.Sprintf("%s %q != %q", key, value, current[key]))
+		}
+	}
+	return mismatch
+}
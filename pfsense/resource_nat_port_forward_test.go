@@ -0,0 +1,339 @@
+package pfsense
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+)
+
+func TestNatDescription(t *testing.T) {
+	cases := []struct {
+		descr       string
+		description string
+		tracker     string
+	}{
+		{"", "", ""},
+		{"web server", "web server", ""},
+		{"[tf:0123456789abcdef]", "", "0123456789abcdef"},
+		{"web server [tf:0123456789abcdef]", "web server", "0123456789abcdef"},
+		{"[tf:0123456789abcdef] web server", "web server", "0123456789abcdef"},
+		{"web [tf:0123456789ABCDEF]", "web [tf:0123456789ABCDEF]", ""},
+		{"web [tf:0123]", "web [tf:0123]", ""},
+	}
+
+	for _, c := range cases {
+		if got := natDescription(c.descr); got != c.description {
+			t.Errorf("natDescription(%q) = %q, want %q", c.descr, got, c.description)
+		}
+		if got := natTracker(c.descr); got != c.tracker {
+			t.Errorf("natTracker(%q) = %q, want %q", c.descr, got, c.tracker)
+		}
+	}
+
+	if got := natTrackerDescr("", "0123456789abcdef"); got != "[tf:0123456789abcdef]" {
+		t.Errorf("natTrackerDescr() without description = %q", got)
+	}
+	descr := natTrackerDescr("web server", "0123456789abcdef")
+	if natDescription(descr) != "web server" || natTracker(descr) != "0123456789abcdef" {
+		t.Errorf("natTrackerDescr() = %q does not split back", descr)
+	}
+}
+
+func TestFindNatRule(t *testing.T) {
+	data := []*api.NatPortForward{
+		{Description: "unmanaged"},
+		{Description: "web [tf:0000000000000001]"},
+		{Description: "[tf:0000000000000002]"},
+	}
+
+	cases := []struct {
+		tracker string
+		id      int
+	}{
+		{"0000000000000001", 1},
+		{"0000000000000002", 2},
+		{"0000000000000003", -1},
+		{"", -1},
+	}
+
+	for _, c := range cases {
+		id, nat := findNatRule(data, c.tracker)
+		if id != c.id {
+			t.Errorf("findNatRule(%q) = %d, want %d", c.tracker, id, c.id)
+		}
+		if id >= 0 && nat != data[id] {
+			t.Errorf("findNatRule(%q) returned the wrong rule", c.tracker)
+		}
+	}
+}
+
+func TestFindNatRuleById(t *testing.T) {
+	rule := func(iface string, port string, descr string) *api.NatPortForward {
+		return &api.NatPortForward{
+			Interface:   iface,
+			Protocol:    "tcp",
+			Source:      &api.NatSourceOrDestination{Any: "1"},
+			Destination: &api.NatSourceOrDestination{Network: "wanip", Port: port},
+			Target:      "10.0.0.2",
+			LocalPort:   port,
+			Description: descr,
+		}
+	}
+	data := []*api.NatPortForward{
+		rule("wan", "80", "web"),
+		rule("wan", "443", "tls [tf:0000000000000001]"),
+		rule("lan", "22", ""),
+	}
+	state := func(iface string, port string) func(string) interface{} {
+		values := map[string]interface{}{
+			"interface":  iface,
+			"protocol":   "tcp",
+			"src":        "any",
+			"dst":        "wanip",
+			"srcport":    "any",
+			"dstport":    port,
+			"target":     "10.0.0.2",
+			"local_port": port,
+		}
+		return func(key string) interface{} { return values[key] }
+	}
+
+	cases := []struct {
+		name  string
+		resId string
+		state func(string) interface{}
+		id    int
+		ok    bool
+	}{
+		{"tracker", "0000000000000001", nil, 1, true},
+		{"tracker gone", "0000000000000009", nil, -1, true},
+		{"invalid id", "wan", nil, -1, false},
+		{"legacy", "wan/0", state("wan", "80"), 0, true},
+		{"legacy with tracker", "wan/1", state("wan", "443"), 1, true},
+		{"legacy shifted", "wan/0", state("wan", "443"), -1, false},
+		{"legacy other interface", "wan/2", state("wan", "22"), -1, false},
+		{"legacy out of range", "wan/3", state("wan", "80"), -1, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id, nat, err := findNatRuleById(data, c.resId, c.state)
+			if c.ok != (err == nil) {
+				t.Fatalf("findNatRuleById(%q) error = %v, want ok %v", c.resId, err, c.ok)
+			}
+			if id != c.id {
+				t.Errorf("findNatRuleById(%q) = %d, want %d", c.resId, id, c.id)
+			}
+			if id >= 0 && nat != data[id] {
+				t.Errorf("findNatRuleById(%q) returned the wrong rule", c.resId)
+			}
+		})
+	}
+}
+
+func TestPlaceNatPortForward(t *testing.T) {
+	cases := []struct {
+		id   int
+		want api.Request
+	}{
+		{0, api.Request{"top": true}},
+		{1, api.Request{"top": false, "after": 0}},
+		{5, api.Request{"top": false, "after": 4}},
+	}
+
+	for _, c := range cases {
+		request := api.Request{}
+		placeNatPortForward(request, c.id)
+		if !reflect.DeepEqual(request, c.want) {
+			t.Errorf("placeNatPortForward(%d) = %v, want %v", c.id, request, c.want)
+		}
+	}
+}
+
+func TestReassociate(t *testing.T) {
+	cases := []struct {
+		associated interface{}
+		want       interface{}
+	}{
+		{"nat_5f0c2e1a3b4d", "add-associated"},
+		{"add-associated", "add-associated"},
+		{"pass", "pass"},
+		{"", ""},
+		{nil, nil},
+	}
+
+	for _, c := range cases {
+		request := api.Request{}
+		if c.associated != nil {
+			request["associated-rule-id"] = c.associated
+		}
+		reassociate(request)
+		if got := request["associated-rule-id"]; got != c.want {
+			t.Errorf("reassociate(%v) = %v, want %v", c.associated, got, c.want)
+		}
+	}
+}
+
+// natApiCall is a request received by natApiServer, the body as decoded from
+// JSON.
+type natApiCall struct {
+	method string
+	body   map[string]interface{}
+}
+
+// natApiServer records the calls on the port forward endpoint and answers
+// each with the status returned by respond.
+type natApiServer struct {
+	mutex   sync.Mutex
+	calls   []natApiCall
+	respond func(method string, n int) int
+}
+
+func (s *natApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	call := natApiCall{method: r.Method}
+	json.NewDecoder(r.Body).Decode(&call.body)
+	n := 0
+	for _, previous := range s.calls {
+		if previous.method == r.Method {
+			n++
+		}
+	}
+	s.calls = append(s.calls, call)
+
+	status := s.respond(r.Method, n)
+	w.WriteHeader(status)
+	w.Write([]byte(`{"code": ` + strconv.Itoa(status) + `, "message": "test", "data": []}`))
+}
+
+func (s *natApiServer) methods() []string {
+	var methods []string
+	for _, call := range s.calls {
+		methods = append(methods, call.method)
+	}
+	return methods
+}
+
+func newNatApiTest(t *testing.T, respond func(method string, n int) int) (*natApiServer, *httptest.Server, *providerConfiguration) {
+	server := &natApiServer{respond: respond}
+	httpServer := httptest.NewServer(server)
+
+	client, err := api.NewClient(api.Config{URL: httpServer.URL, AuthMode: api.AuthModeToken})
+	if err != nil {
+		httpServer.Close()
+		t.Fatal(err)
+	}
+	return server, httpServer, &providerConfiguration{Client: client}
+}
+
+func TestUpdateNatPortForwardFallback(t *testing.T) {
+	server, httpServer, pconf := newNatApiTest(t, func(method string, n int) int {
+		if method == http.MethodPut {
+			return 405
+		}
+		return 200
+	})
+	defer httpServer.Close()
+
+	original := &api.NatPortForward{AssociatedRuleId: "nat_5f0c2e1a3b4d"}
+	request := api.Request{"descr": "web", "associated-rule-id": original.AssociatedRuleId}
+	if err := updateNatPortForward(pconf, 2, original, request); err != nil {
+		t.Fatal(err)
+	}
+	if !pconf.natPutUnsupported {
+		t.Error("a 405 on PUT did not mark updates as unsupported")
+	}
+	want := []string{http.MethodPut, http.MethodDelete, http.MethodPost}
+	if got := server.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+
+	deleted := server.calls[1].body
+	if deleted["id"] != 2.0 {
+		t.Errorf("deleted rule %v, want 2", deleted["id"])
+	}
+	created := server.calls[2].body
+	if _, ok := created["id"]; ok {
+		t.Errorf("recreated rule carries the id %v", created["id"])
+	}
+	if created["top"] != false || created["after"] != 1.0 {
+		t.Errorf("recreated rule placed with top %v after %v, want after 1", created["top"], created["after"])
+	}
+	if created["associated-rule-id"] != "add-associated" {
+		t.Errorf("recreated rule associated with %v, want a new filter rule", created["associated-rule-id"])
+	}
+
+	// Once PUT is known to be missing, updates go straight to the recreate.
+	server.calls = nil
+	if err := updateNatPortForward(pconf, 0, original, api.Request{"descr": "web"}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{http.MethodDelete, http.MethodPost}
+	if got := server.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	if server.calls[1].body["top"] != true {
+		t.Errorf("rule recreated at index 0 not placed at the top")
+	}
+}
+
+func TestUpdateNatPortForwardNotFound(t *testing.T) {
+	server, httpServer, pconf := newNatApiTest(t, func(method string, n int) int {
+		return 404
+	})
+	defer httpServer.Close()
+
+	err := updateNatPortForward(pconf, 2, &api.NatPortForward{}, api.Request{"descr": "web"})
+	if err == nil {
+		t.Fatal("update of a missing rule succeeded")
+	}
+	if pconf.natPutUnsupported {
+		t.Error("a 404 on PUT marked updates as unsupported")
+	}
+	if got := server.methods(); !reflect.DeepEqual(got, []string{http.MethodPut}) {
+		t.Errorf("calls = %v, want only the PUT", got)
+	}
+}
+
+func TestRecreateNatPortForwardRollback(t *testing.T) {
+	server, httpServer, pconf := newNatApiTest(t, func(method string, n int) int {
+		if method == http.MethodPost && n == 0 {
+			return 400
+		}
+		return 200
+	})
+	defer httpServer.Close()
+
+	original := &api.NatPortForward{
+		Interface:   "wan",
+		Protocol:    "tcp",
+		Source:      &api.NatSourceOrDestination{Any: "1"},
+		Destination: &api.NatSourceOrDestination{Network: "wanip", Port: "80"},
+		Target:      "10.0.0.2",
+		LocalPort:   "80",
+		Description: "web [tf:0000000000000001]",
+	}
+	err := recreateNatPortForward(pconf.Client, 3, original, api.Request{"descr": "invalid"}, false)
+	if err == nil {
+		t.Fatal("rejected recreate succeeded")
+	}
+	want := []string{http.MethodDelete, http.MethodPost, http.MethodPost}
+	if got := server.methods(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("calls = %v, want %v", got, want)
+	}
+	restored := server.calls[2].body
+	if restored["descr"] != original.Description || restored["target"] != original.Target {
+		t.Errorf("rollback created %v, want the original rule", restored)
+	}
+	if restored["after"] != 2.0 {
+		t.Errorf("rollback placed after %v, want 2", restored["after"])
+	}
+}
@@ -0,0 +1,162 @@
+package pfsense
+
+import (
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"log"
+	"strings"
+)
+
+// Router advertisements are stored with the DHCPv6 settings of the interface,
+// they are read from the same listing and written under the same lock.
+func resourceRouterAdvertisement() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRouterAdvertisementCreate,
+		Read:   resourceRouterAdvertisementRead,
+		Update: resourceRouterAdvertisementUpdate,
+		Delete: resourceRouterAdvertisementDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"mode": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice([]string{"disabled", "router", "unmanaged", "managed", "assist", "stateless_dhcp"}, false),
+			},
+			"priority": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "medium",
+				ValidateFunc: validation.StringInSlice([]string{"low", "medium", "high"}, false),
+			},
+			"use_dhcpv6_dns": {
+				Type:          schema.TypeBool,
+				Optional:      true,
+				Default:       false,
+				ConflictsWith: []string{"dns_servers", "domain_search_list"},
+			},
+			"dns_servers": {
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 3,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.IsIPv6Address,
+				},
+			},
+			"domain_search_list": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+		},
+	}
+}
+
+func resourceRouterAdvertisementCreate(d *schema.ResourceData, meta interface{}) error {
+	err := resourceRouterAdvertisementUpdate(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(d.Get("interface").(string))
+	return resourceRouterAdvertisementRead(d, meta)
+}
+
+func resourceRouterAdvertisementRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	iface := d.Id()
+	data, err := pconf.dhcpv6Server(iface)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		log.Printf("[WARN] router advertisement on %s not found, removing it from state", iface)
+		d.SetId("")
+		return nil
+	}
+
+	values := map[string]interface{}{
+		"interface":          iface,
+		"mode":               routerAdvertisementMode(data.RaMode),
+		"priority":           routerAdvertisementPriority(data.RaPriority),
+		"use_dhcpv6_dns":     bool(data.RaSameDnsAsDhcp),
+		"dns_servers":        []string(data.RaDnsServers),
+		"domain_search_list": splitDomainSearchList(data.RaDomainSearchList),
+	}
+	for key, value := range values {
+		err = d.Set(key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceRouterAdvertisementUpdate(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	request := api.Request{
+		"interface":          d.Get("interface").(string),
+		"ramode":             d.Get("mode").(string),
+		"rapriority":         d.Get("priority").(string),
+		"rasamednsasdhcp6":   d.Get("use_dhcpv6_dns").(bool),
+		"radnsserver":        stringList(d.Get("dns_servers").([]interface{})),
+		"radomainsearchlist": strings.Join(stringList(d.Get("domain_search_list").([]interface{})), ";"),
+		"apply":              pconf.applyNow(),
+	}
+
+	return pconf.write(dhcpv6Section(d.Get("interface").(string)), func() error {
+		return client.UpdateRouterAdvertisement(request)
+	})
+}
+
+// Deleting the resource stops the advertisements on the interface.
+func resourceRouterAdvertisementDelete(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	iface := d.Id()
+
+	return pconf.write(dhcpv6Section(iface), func() error {
+		err := client.UpdateRouterAdvertisement(api.Request{
+			"interface": iface,
+			"ramode":    "disabled",
+			"apply":     pconf.applyDeleteNow(),
+		})
+		if api.IsNotFound(err) {
+			log.Printf("[WARN] router advertisement on %s already deleted", iface)
+			return nil
+		}
+		return err
+	})
+}
+
+func routerAdvertisementMode(mode string) string {
+	if len(mode) <= 0 {
+		return "disabled"
+	}
+	return mode
+}
+
+func routerAdvertisementPriority(priority string) string {
+	if len(priority) <= 0 {
+		return "medium"
+	}
+	return priority
+}
@@ -0,0 +1,250 @@
+package pfsense
+
+import (
+	"fmt"
+	"github.com/ShadowSteps/terraform-provider-pfsense/pfsense/api"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/helper/validation"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ruleOrderFilter         = "filter"
+	ruleOrderNatPortForward = "nat_port_forward"
+)
+
+// pfsense_rule_order keeps a list of managed rules in the given order. The
+// rules are moved to the top of the rule list one by one, so they end up above
+// any rule that is not part of the list.
+func resourceRuleOrder() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRuleOrderCreate,
+		Read:   resourceRuleOrderRead,
+		Update: resourceRuleOrderUpdate,
+		Delete: resourceRuleOrderDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+
+		Schema: map[string]*schema.Schema{
+			"type": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{ruleOrderFilter, ruleOrderNatPortForward}, false),
+			},
+			"interface": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+			},
+			"rules": {
+				Type:     schema.TypeList,
+				Required: true,
+				MinItems: 1,
+				Elem: &schema.Schema{
+					Type:         schema.TypeString,
+					ValidateFunc: validation.All(validation.StringIsNotEmpty, validation.StringIsNotWhiteSpace),
+				},
+			},
+		},
+	}
+}
+
+func resourceRuleOrderCreate(d *schema.ResourceData, meta interface{}) error {
+	err := applyRuleOrder(d, meta)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(ruleOrderResourceId(d.Get("type").(string), d.Get("interface").(string)))
+	return resourceRuleOrderRead(d, meta)
+}
+
+func resourceRuleOrderRead(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+
+	typ, iface, err := parseRuleOrderResourceId(d.Id())
+	if err != nil {
+		d.SetId("")
+		return err
+	}
+
+	positions, err := ruleOrderPositions(pconf, typ, iface)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(positions))
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return positions[ids[i]] < positions[ids[j]]
+	})
+
+	// Report the rules of the interface from the top down to the last
+	// configured one, in the order they currently have. A configured rule
+	// moved in the GUI, or any other rule placed above one, shows up as a
+	// diff. Rules that no longer exist on the interface are left out.
+	configured := map[string]bool{}
+	for _, id := range d.Get("rules").([]interface{}) {
+		configured[id.(string)] = true
+	}
+	last := -1
+	for i, id := range ids {
+		if configured[id] {
+			last = i
+		}
+	}
+	ordered := ids[:last+1]
+
+	// On import nothing is configured yet, so every rule of the interface
+	// that can be ordered is taken over.
+	if len(configured) <= 0 {
+		ordered = nil
+		for _, id := range ids {
+			if !strings.HasPrefix(id, ruleOrderUntracked) {
+				ordered = append(ordered, id)
+			}
+		}
+	}
+
+	err = d.Set("type", typ)
+	if err != nil {
+		return err
+	}
+	err = d.Set("interface", iface)
+	if err != nil {
+		return err
+	}
+	return d.Set("rules", ordered)
+}
+
+func resourceRuleOrderUpdate(d *schema.ResourceData, meta interface{}) error {
+	err := applyRuleOrder(d, meta)
+	if err != nil {
+		return err
+	}
+	return resourceRuleOrderRead(d, meta)
+}
+
+func resourceRuleOrderDelete(d *schema.ResourceData, meta interface{}) error {
+	d.SetId("")
+	return nil
+}
+
+// Port forwards without a tracker are not managed by the provider and cannot
+// be ordered, they are reported by their position only.
+const ruleOrderUntracked = "untracked:"
+
+// ruleOrderPositions maps the id of every rule on iface to its position in
+// the rule list.
+func ruleOrderPositions(pconf *providerConfiguration, typ string, iface string) (map[string]int, error) {
+	positions := map[string]int{}
+
+	switch typ {
+	case ruleOrderFilter:
+		rules, err := pconf.firewallRules()
+		if err != nil {
+			return nil, err
+		}
+		for position, rule := range rules {
+			if rule.Interface == iface {
+				positions[string(rule.Tracker)] = position
+			}
+		}
+	case ruleOrderNatPortForward:
+		rules, err := pconf.natPortForwards()
+		if err != nil {
+			return nil, err
+		}
+		for position, rule := range rules {
+			if rule.Interface != iface {
+				continue
+			}
+			tracker := natTracker(rule.Description)
+			if len(tracker) <= 0 {
+				tracker = fmt.Sprintf("%s%d", ruleOrderUntracked, position)
+			}
+			positions[tracker] = position
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rule order type: %s", typ)
+	}
+
+	return positions, nil
+}
+
+// applyRuleOrder moves the rules to the top of the list starting from the
+// last one, which leaves them in the configured order.
+func applyRuleOrder(d *schema.ResourceData, meta interface{}) error {
+	pconf := meta.(*providerConfiguration)
+	client := pconf.Client
+
+	typ := d.Get("type").(string)
+	iface := d.Get("interface").(string)
+	rules := d.Get("rules").([]interface{})
+
+	section := sectionFilter
+	if typ == ruleOrderNatPortForward {
+		section = sectionNat
+	}
+
+	return pconf.write(section, func() error {
+		for i := len(rules) - 1; i >= 0; i-- {
+			id := rules[i].(string)
+			apply := i == 0 && pconf.applyNow()
+
+			switch typ {
+			case ruleOrderFilter:
+				err := client.UpdateFirewallRule(api.Request{
+					"tracker": id,
+					"top":     true,
+					"apply":   apply,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to move firewall rule %s! %s", id, err)
+				}
+			case ruleOrderNatPortForward:
+				data, err := client.ListNatPortForwards()
+				if err != nil {
+					return err
+				}
+				position, nat := findNatRule(data, id)
+				if position < 0 {
+					return fmt.Errorf("NAT rule for this tracker do not exists! tracker: %s", id)
+				}
+				if nat.Interface != iface {
+					return fmt.Errorf("NAT rule %s is on interface %s, not %s", id, nat.Interface, iface)
+				}
+				request := natPortForwardRequestFromRule(nat)
+				request["top"] = true
+				err = updateNatPortForward(pconf, position, nat, request)
+				if err != nil {
+					return fmt.Errorf("failed to move NAT rule %s! %s", id, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func ruleOrderResourceId(typ string, iface string) string {
+	return fmt.Sprintf("%s/%s", typ, iface)
+}
+
+var ruleOrderRsId = regexp.MustCompile("^(filter|nat_port_forward)/([^/]+)$")
+
+func parseRuleOrderResourceId(resId string) (typ string, iface string, err error) {
+	if !ruleOrderRsId.MatchString(resId) {
+		return "", "", fmt.Errorf("invalid resource format: %s. must be type/interface", resId)
+	}
+	idMatch := ruleOrderRsId.FindStringSubmatch(resId)
+	typ = idMatch[1]
+	iface = idMatch[2]
+	return
+}